@@ -0,0 +1,353 @@
+// Command gowhere translates a filter to the SQL WHERE clause, e.g: to debug the filters of a support ticket.
+//
+// Usage:
+//
+//	gowhere [flags] [filter]
+//
+// The filter is either a JSON object/array, e.g: '{"name__icontains": "go"}', or a query string,
+// e.g: 'name__icontains=go&id__in=1,2'. It's read from stdin if not given or "-".
+//
+// Flags:
+//
+//	-dialect  postgres (default) or mysql
+//	-config   the JSON config file: {"table": "trips", "strict": true, "aliases": {"name": "full_name"},
+//	          "allowed_fields": {"name": ["icontains"]}, "time_fields": ["created_at"]}
+//	-table    the table of the columns, overrides the config file
+//	-strict   report the invalid conditions as errors, overrides the config file
+//	-format   text (default) or json
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imdatngo/gowhere"
+)
+
+// fileConfig is the content of the config file
+type fileConfig struct {
+	Table         string              `json:"table"`
+	Strict        bool                `json:"strict"`
+	Aliases       map[string]string   `json:"aliases"`
+	AllowedFields map[string][]string `json:"allowed_fields"`
+	TimeFields    []string            `json:"time_fields"`
+}
+
+// problem is a single error or warning of the output
+type problem struct {
+	Path     string      `json:"path,omitempty"`
+	Field    string      `json:"field,omitempty"`
+	Operator string      `json:"operator,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+	Reason   string      `json:"reason,omitempty"`
+	Message  string      `json:"message"`
+}
+
+// output is the result of the translation
+type output struct {
+	SQL          string        `json:"sql"`
+	Vars         []interface{} `json:"vars"`
+	Interpolated string        `json:"interpolated"`
+	Errors       []problem     `json:"errors"`
+	Warnings     []problem     `json:"warnings"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run translates the filter, and returns the exit code: 0 on success, 1 if the plan has errors and 2 for invalid usages
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gowhere", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dialect := flags.String("dialect", gowhere.DialectPostgreSQLName, "the SQL dialect: postgres or mysql")
+	configFile := flags.String("config", "", "the JSON config file with table, strict, aliases, allowed_fields & time_fields")
+	table := flags.String("table", "", "the table of the columns, overrides the config file")
+	strict := flags.Bool("strict", false, "report the invalid conditions as errors, overrides the config file")
+	format := flags.String("format", "text", "the output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(stderr, "gowhere:", err)
+		return 2
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "table":
+			cfg.Table = *table
+		case "strict":
+			cfg.Strict = *strict
+		}
+	})
+	switch *dialect {
+	case gowhere.DialectPostgreSQLName, "postgresql":
+		cfg.Dialect = gowhere.DialectPostgreSQL
+	case gowhere.DialectMySQLName:
+		cfg.Dialect = gowhere.DialectMySQL
+	default:
+		fmt.Fprintf(stderr, "gowhere: unknown dialect %q\n", *dialect)
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "gowhere: unknown format %q\n", *format)
+		return 2
+	}
+
+	input := flags.Arg(0)
+	if input == "" || input == "-" {
+		raw, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "gowhere:", err)
+			return 2
+		}
+		input = string(raw)
+	}
+	filter, err := parseFilter(input, cfg.Separator)
+	if err != nil {
+		fmt.Fprintln(stderr, "gowhere: invalid filter:", err)
+		return 2
+	}
+
+	out := translate(gowhere.WithConfig(cfg).Where(filter), cfg)
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(out); err != nil {
+			fmt.Fprintln(stderr, "gowhere:", err)
+			return 2
+		}
+	} else {
+		printText(stdout, out)
+	}
+
+	if len(out.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadConfig reads the config file if given
+func loadConfig(path string) (gowhere.Config, error) {
+	cfg := gowhere.Config{Separator: gowhere.DefaultConfig.Separator}
+	if path == "" {
+		return cfg, nil
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	var fc fileConfig
+	if err := json.Unmarshal(raw, &fc); err != nil {
+		return cfg, fmt.Errorf("invalid config file: %v", err)
+	}
+	cfg.Table = fc.Table
+	cfg.Strict = fc.Strict
+	cfg.ColumnAliases = fc.Aliases
+	cfg.AllowedFields = fc.AllowedFields
+	cfg.TimeFields = fc.TimeFields
+	return cfg, nil
+}
+
+// parseFilter parses the filter as JSON if it looks like a JSON object or array, otherwise as a query string
+func parseFilter(input, separator string) (interface{}, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, errors.New("empty filter")
+	}
+	if input[0] == '{' || input[0] == '[' {
+		var filter interface{}
+		if err := json.Unmarshal([]byte(input), &filter); err != nil {
+			return nil, err
+		}
+		return filter, nil
+	}
+
+	values, err := url.ParseQuery(strings.TrimPrefix(input, "?"))
+	if err != nil {
+		return nil, err
+	}
+	filter := make(map[string]interface{}, len(values))
+	for key, vals := range values {
+		operator := ""
+		if i := strings.LastIndex(key, separator); i > 0 {
+			operator = key[i+len(separator):]
+		}
+		switch {
+		case operator == "isnull":
+			null, err := strconv.ParseBool(vals[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			filter[key] = null
+		case operator == "in" || operator == "notin" || operator == "between" || operator == "datebetween":
+			// comma separated, e.g: id__in=1,2,3
+			list := make([]interface{}, 0)
+			for _, v := range vals {
+				for _, item := range strings.Split(v, ",") {
+					list = append(list, item)
+				}
+			}
+			filter[key] = list
+		case len(vals) > 1:
+			list := make([]interface{}, len(vals))
+			for i, v := range vals {
+				list[i] = v
+			}
+			filter[key] = list
+		default:
+			filter[key] = vals[0]
+		}
+	}
+	return filter, nil
+}
+
+// translate builds the plan into the output
+func translate(plan *gowhere.Plan, cfg gowhere.Config) output {
+	out := output{
+		SQL:      plan.SQL(),
+		Vars:     plan.Vars(),
+		Errors:   problems(plan.Error),
+		Warnings: problems(plan.Warnings()),
+	}
+	if out.Vars == nil {
+		out.Vars = []interface{}{}
+	}
+	out.Interpolated = interpolate(out.SQL, out.Vars, cfg.Dialect)
+	return out
+}
+
+// problems converts the errors of the plan to the output
+func problems(err error) []problem {
+	list := make([]problem, 0)
+	if err == nil {
+		return list
+	}
+	var errs gowhere.Errors
+	if !errors.As(err, &errs) {
+		return append(list, problem{Message: err.Error()})
+	}
+	for _, e := range errs {
+		list = append(list, problem{
+			Path:     e.Path,
+			Field:    e.Field,
+			Operator: e.Operator,
+			Value:    e.Value,
+			Reason:   e.Reason,
+			Message:  e.Err.Error(),
+		})
+	}
+	return list
+}
+
+// interpolate replaces the "?" placeholders with the literals of the vars, except the ones in the quoted strings &
+// identifiers. For debugging only, the result must not be executed
+func interpolate(sql string, vars []interface{}, dialect gowhere.Dialect) string {
+	var b strings.Builder
+	var quote rune
+	i := 0
+	for _, r := range sql {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?' && i < len(vars):
+			b.WriteString(literal(vars[i], dialect))
+			i++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// literal returns the SQL literal of the value
+func literal(value interface{}, dialect gowhere.Dialect) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint16, uint32, uint64, float32, float64, json.Number:
+		return fmt.Sprint(v)
+	case time.Time:
+		return literal(v.Format("2006-01-02 15:04:05.999999999-07:00"), dialect)
+	case []byte:
+		return literal(string(v), dialect)
+	}
+
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		// the slices are expanded, e.g: IN (1, 2)
+		if rv.Len() == 0 {
+			return "NULL"
+		}
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = literal(rv.Index(i).Interface(), dialect)
+		}
+		return strings.Join(items, ", ")
+	}
+
+	s := gowhere.Utils.ToString(value)
+	s = strings.Replace(s, "'", "''", -1)
+	if dialect.GetName() == gowhere.DialectMySQLName {
+		s = strings.Replace(s, `\`, `\\`, -1)
+	}
+	return "'" + s + "'"
+}
+
+// printText prints the output for humans
+func printText(w io.Writer, out output) {
+	fmt.Fprintln(w, "SQL:         ", out.SQL)
+	fmt.Fprintln(w, "Vars:        ", formatVars(out.Vars))
+	if out.Interpolated != "" {
+		fmt.Fprintln(w, "Interpolated:", out.Interpolated)
+	}
+	printProblems(w, "Errors:", out.Errors)
+	printProblems(w, "Warnings:", out.Warnings)
+}
+
+// formatVars formats the vars as JSON, so the types can be told apart, e.g: 1 and "1"
+func formatVars(vars []interface{}) string {
+	raw, err := json.Marshal(vars)
+	if err != nil {
+		return fmt.Sprint(vars)
+	}
+	return string(raw)
+}
+
+// printProblems prints the list of errors or warnings, sorted by the path
+func printProblems(w io.Writer, title string, list []problem) {
+	if len(list) == 0 {
+		return
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	fmt.Fprintln(w, title)
+	for _, p := range list {
+		if p.Reason != "" {
+			fmt.Fprintf(w, "  %s: %s (%s)\n", p.Path, p.Message, p.Reason)
+		} else {
+			fmt.Fprintf(w, "  %s\n", p.Message)
+		}
+	}
+}
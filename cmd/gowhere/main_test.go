@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gowhere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(config, []byte(`{"table": "trips", "strict": true, "aliases": {"name": "full_name"},
+		"allowed_fields": {"name": ["icontains"], "id": null}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantCode int
+		wantOut  string
+		wantErr  string
+	}{
+		{
+			name: "query string",
+			args: []string{"name__icontains=go&id__in=1,2&ended_at__isnull=true"},
+			wantOut: `SQL:          ("ended_at" IS NULL AND "id" IN (?) AND LOWER("name") LIKE LOWER(?))
+Vars:         [["1","2"],"%go%"]
+Interpolated: ("ended_at" IS NULL AND "id" IN ('1', '2') AND LOWER("name") LIKE LOWER('%go%'))
+`,
+		},
+		{
+			name:  "stdin json with warnings",
+			args:  []string{"-dialect", "mysql", "-table", "trips"},
+			stdin: `{"name": "it's", "budget__bad": 1}`,
+			wantOut: "SQL:          (`trips`.`name` = ?)\n" +
+				"Vars:         [\"it's\"]\n" +
+				"Interpolated: (`trips`.`name` = 'it''s')\n" +
+				"Warnings:\n" +
+				"  [0].budget__bad: unknown operator \"bad\" of field \"budget\" (unknown_operator)\n",
+		},
+		{
+			name:     "config file",
+			args:     []string{"-config", config, "-format", "json", `{"name__icontains": "go", "budget": 1}`},
+			wantCode: 1,
+			wantOut: `{
+  "sql": "(LOWER(\"trips\".\"full_name\") LIKE LOWER(?))",
+  "vars": [
+    "%go%"
+  ],
+  "interpolated": "(LOWER(\"trips\".\"full_name\") LIKE LOWER('%go%'))",
+  "errors": [
+    {
+      "path": "[0].budget",
+      "field": "budget",
+      "value": 1,
+      "reason": "unknown_field",
+      "message": "unknown field \"budget\""
+    }
+  ],
+  "warnings": []
+}
+`,
+		},
+		{
+			name:     "invalid filter",
+			args:     []string{"{"},
+			wantCode: 2,
+			wantErr:  "gowhere: invalid filter: unexpected end of JSON input\n",
+		},
+		{
+			name:     "unknown dialect",
+			args:     []string{"-dialect", "oracle", "id=1"},
+			wantCode: 2,
+			wantErr:  "gowhere: unknown dialect \"oracle\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("run() = %v, want %v, stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			if got := stdout.String(); got != tt.wantOut {
+				t.Errorf("run() stdout = %v, want %v", got, tt.wantOut)
+			}
+			if got := stderr.String(); got != tt.wantErr {
+				t.Errorf("run() stderr = %v, want %v", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	filter, err := parseFilter("?id=1&id=2&paid_at__between=2020-01-01,2020-02-01", "__")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := filter.(map[string]interface{})
+	if ids := got["id"].([]interface{}); len(ids) != 2 || ids[1] != "2" {
+		t.Errorf("parseFilter() id = %v", got["id"])
+	}
+	if r := got["paid_at__between"].([]interface{}); len(r) != 2 || r[0] != "2020-01-01" {
+		t.Errorf("parseFilter() paid_at__between = %v", got["paid_at__between"])
+	}
+	if _, err := parseFilter("ended_at__isnull=maybe", "__"); err == nil {
+		t.Errorf("parseFilter() accepts an invalid boolean")
+	}
+}
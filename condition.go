@@ -1,6 +1,7 @@
 package gowhere
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 )
@@ -31,9 +32,134 @@ type rawConditions struct {
 	not    bool
 }
 
+// inputConditions wraps a condition given to Where/Or/Not/Scope to keep track of its position in the input
+type inputConditions struct {
+	index int
+	cond  condition
+	scope bool
+}
+
+// invalidConditions keeps an unsupported condition given to Where/Or/Not, so it can be reported on build
+type invalidConditions struct {
+	cond interface{}
+	vars []interface{}
+}
+
 // condition represents the condition interface
 type condition interface {
-	build(cfg *Config) (string, []interface{})
+	// build renders the condition by the renderer of the state. Returns nil if the condition is skipped
+	build(s *buildState) interface{}
+}
+
+// buildState carries the config and the found problems while building the conditions tree
+type buildState struct {
+	cfg *Config
+	// the renderer of the nodes, i.e: Config.Renderer or SQLRenderer for Build, or the renderer given to Render
+	renderer Renderer
+	// whether the nodes are *SQLNode, i.e: built by Build. Relations, subqueries & expressions are only built into SQL
+	sql bool
+	// the location of the plan in the input, i.e: empty for the main plan, or the path of the condition using a subquery
+	root string
+	// the location of the current condition in the input
+	path string
+	// shared between all states of the same build
+	errs *Errors
+	// problems which are always reported as errors and fail the plan closed, e.g: of the scopes or missing context values
+	fatalErrs *Errors
+	// problems of the renderer other than SQL, which fail Render regardless of Config.Strict. Nil for Build
+	renderErrs *Errors
+	// conditions given by the code, e.g: result of custom conditions, are not checked against the allowed fields
+	trusted bool
+	// whether the current condition is a part of the scopes
+	scope bool
+	// the path of relations to the current map conditions, e.g: "author__company__"
+	prefix string
+	// the clause being built, i.e: clauseAll unless there are aggregate fields
+	clause int
+}
+
+// at returns a copy of the state for a nested condition
+func (s *buildState) at(path string) *buildState {
+	ns := *s
+	ns.path = s.path + path
+	return &ns
+}
+
+// report records a problem of the current condition
+func (s *buildState) report(e *ConditionError) {
+	if e.Path == "" {
+		e.Path = s.path
+	}
+	if s.scope {
+		s.fail(e)
+		return
+	}
+	*s.errs = append(*s.errs, e)
+}
+
+// fail records a problem which is always reported as an error and fails the plan closed, regardless of Config.Strict
+func (s *buildState) fail(e *ConditionError) {
+	if e.Path == "" {
+		e.Path = s.path
+	}
+	*s.fatalErrs = append(*s.fatalErrs, e)
+}
+
+// render returns the node rendered by the renderer, reporting its error if any. Build requires *SQLNode
+func (s *buildState) render(node interface{}, err error) interface{} {
+	if err == nil && node != nil && s.sql {
+		if _, ok := node.(*SQLNode); !ok {
+			err = fmt.Errorf("the renderer of Build must return *SQLNode, got %T", node)
+		}
+	}
+	if err == nil {
+		return node
+	}
+
+	if s.renderErrs == nil {
+		s.report(&ConditionError{Reason: ReasonNotRenderable, Err: err})
+		return nil
+	}
+	e, ok := err.(*ConditionError)
+	if !ok {
+		e = &ConditionError{Reason: ReasonNotRenderable, Err: err}
+	}
+	if e.Path == "" {
+		e.Path = s.path
+	}
+	*s.renderErrs = append(*s.renderErrs, e)
+	return nil
+}
+
+// unsupported reports the condition which is only built into SQL, e.g: a relation rendered for MongoDB
+func (s *buildState) unsupported(what string) interface{} {
+	if u, ok := s.renderer.(interface{ unsupported(what string) error }); ok {
+		return s.render(nil, u.unsupported(what))
+	}
+	return s.render(nil, &NotRenderableError{Condition: what, Target: rendererTarget(s.renderer)})
+}
+
+// group renders the group of the built items, skipping the empty ones. Returns nil if there are no items
+func (s *buildState) group(g *Group, items []interface{}) interface{} {
+	g.Path = s.path
+	for _, item := range items {
+		if n, ok := item.(*SQLNode); item == nil || (ok && n.SQL == "") {
+			continue
+		}
+		g.Items = append(g.Items, item)
+	}
+	if len(g.Items) == 0 {
+		return nil
+	}
+	return s.render(s.renderer.Group(g))
+}
+
+// sqlOf returns the SQL & the vars of the node built by Build, or empty if it's skipped
+func sqlOf(node interface{}) (string, []interface{}) {
+	if n, ok := node.(*SQLNode); ok {
+		return n.SQL, n.Vars
+	}
+	return "", []interface{}{}
 }
 
 // CustomConditionFn represents the func signature which provide full access on the condition generating.
@@ -41,176 +167,331 @@ type condition interface {
 // Return nil will exclude the condition from result
 type CustomConditionFn func(key string, val interface{}, cfg *Config) interface{}
 
-func (ac *andConditions) build(cfg *Config) (string, []interface{}) {
-	sqls, vars := listBuild(ac.value, cfg)
-	sql := strings.Join(sqls, " AND ")
-	if sql != "" {
-		if !ac.naked || ac.not {
-			sql = "(" + sql + ")"
-			if ac.not {
-				sql = "NOT " + sql
-			}
-		}
+func (ac *andConditions) build(s *buildState) interface{} {
+	if ac.not && s.mixed(ac.value) {
+		return nil
 	}
+	return s.group(&Group{Not: ac.not, Root: ac.naked}, listBuild(ac.value, s))
+}
 
-	return sql, vars
+func (oc *orConditions) build(s *buildState) interface{} {
+	if s.mixed(oc.value) {
+		return nil
+	}
+	return s.group(&Group{Or: true, Not: oc.not}, listBuild(oc.value, s))
 }
 
-func (oc *orConditions) build(cfg *Config) (string, []interface{}) {
-	sqls, vars := listBuild(oc.value, cfg)
-	sql := strings.Join(sqls, " OR ")
-	if sql != "" {
-		sql = "(" + sql + ")"
-		if oc.not {
-			sql = "NOT " + sql
+func (rc *rawConditions) build(s *buildState) interface{} {
+	if s.clause == clauseHaving {
+		return nil
+	}
+	if reason := rc.validate(); reason != "" {
+		// the raw conditions are given by the code, e.g: the ownership filters, so they're never skipped
+		s.fail(&ConditionError{
+			Value:  rc.clause,
+			Reason: ReasonInvalidRaw,
+			Err:    &InvalidRawError{Clause: rc.clause, Vars: rc.vars, Reason: reason},
+		})
+		return nil
+	}
+
+	vars := make([]interface{}, len(rc.vars))
+	for i, v := range rc.vars {
+		res, err := resolveValue(v, s.cfg)
+		if err != nil {
+			s.fail(&ConditionError{
+				Value:  rc.clause,
+				Reason: ReasonInvalidRaw,
+				Err:    &InvalidRawError{Clause: rc.clause, Vars: rc.vars, Reason: err.Error()},
+			})
+			return nil
 		}
+		vars[i] = res
+	}
+
+	if rc.clause == "" {
+		return nil
 	}
+	return s.render(s.renderer.Raw(&Raw{Path: s.path, Clause: rc.clause, Vars: vars, Not: rc.not}))
+}
 
-	return sql, vars
+// validate returns the reason why the raw condition is malformed, if any.
+// The placeholders are only counted if vars are given, as "?" is also a valid operator in some dialects, e.g: jsonb in PostgreSQL.
+// The "?" in the quoted strings & identifiers are not placeholders
+func (rc *rawConditions) validate() string {
+	if len(rc.vars) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(rc.clause) == "" {
+		return "empty clause with vars"
+	}
+	n := 0
+	replacePlaceholders(rc.clause, func() string { n++; return "?" })
+	if n != len(rc.vars) {
+		return fmt.Sprintf("%d placeholder(s) but %d var(s)", n, len(rc.vars))
+	}
+	return ""
 }
 
-func (rc *rawConditions) build(cfg *Config) (string, []interface{}) {
-	sql := rc.clause
-	if sql != "" {
-		sql = "(" + sql + ")"
-		if rc.not {
-			sql = "NOT " + sql
-		}
+func (ic *inputConditions) build(s *buildState) interface{} {
+	ns := *s
+	ns.path = fmt.Sprintf("%s[%d]", s.root, ic.index)
+	if ic.scope {
+		// scopes are given by the code, so they're not checked against the allowed fields
+		ns.path = fmt.Sprintf("%sscope[%d]", s.root, ic.index)
+		ns.scope = true
+		ns.trusted = true
 	}
-	return sql, rc.vars
+	return ic.cond.build(&ns)
 }
 
-func (mc *mapConditions) build(cfg *Config) (string, []interface{}) {
+func (ic *invalidConditions) build(s *buildState) interface{} {
+	if s.clause == clauseHaving {
+		return nil
+	}
+	s.report(&ConditionError{
+		Value:  ic.cond,
+		Reason: ReasonUnsupportedType,
+		Err:    &UnsupportedTypeError{Value: ic.cond},
+	})
+	return nil
+}
+
+func (mc *mapConditions) build(s *buildState) interface{} {
+	if mc.not && len(mc.value) > 1 && s.mixed(mc.value) {
+		return nil
+	}
+	cfg := s.cfg
 	vlen := len(mc.value)
-	sqls := make([]string, 0, vlen)
-	vars := make([]interface{}, 0)
+	items := make([]interface{}, 0, vlen)
 
-	processFunc := func(key string, val interface{}) {
-		var _sql string
-		var _vars []interface{}
+	processFunc := func(key string, val interface{}) interface{} {
+		var field, opName string
+		ks := s.at("." + s.prefix + key)
+		report := func(reason string, err error) {
+			ks.report(&ConditionError{Field: s.prefix + field, Operator: opName, Value: val, Reason: reason, Err: err})
+		}
+		// the values which can't be resolved, e.g: missing in the context, are bugs of the code rather than bad inputs
+		fail := func(err error) {
+			ks.fail(&ConditionError{Field: s.prefix + field, Operator: opName, Value: val, Reason: ReasonInvalidValue,
+				Err: &InvalidValueError{Field: s.prefix + field, Operator: opName, Value: val, Err: err}})
+		}
+		value, verr := resolveValue(val, cfg)
+		if verr == nil {
+			val = value
+		}
 
 		if customCondFn, ok := cfg.CustomConditions[key]; ok {
+			// custom conditions are always built into the WHERE clause
+			if s.clause == clauseHaving {
+				return nil
+			}
+			field = key
+			if verr != nil {
+				fail(verr)
+				return nil
+			}
 			rawCond := customCondFn(key, val, cfg)
 			if rawCond == nil {
-				return
+				return nil
 			}
 			cond, err := toCondition(rawCond, []interface{}{}, false)
 			if err != nil {
-				if cfg.Strict {
-					panic(&InvalidCond{cond: rawCond})
-				}
-				return
+				report(ReasonUnsupportedType, err)
+				return nil
 			}
-			_sql, _vars = cond.build(cfg)
+			ks.trusted = true
+			ks.prefix = ""
+			ks.clause = clauseAll
+			return cond.build(ks)
+		}
+
+		res := strings.Split(key, cfg.Separator)
+		field = res[0]
+		if len(res) > 1 {
+			opName = res[1]
+		}
 
+		if s.clause != clauseAll && cfg.isAggregateCondition(field, val) != (s.clause == clauseHaving) {
+			// built by the other clause
+			return nil
+		}
+
+		if verr != nil {
+			fail(verr)
+			return nil
+		}
+
+		if !ks.trusted && !cfg.isAllowedField(field) {
+			report(ReasonUnknownField, &UnknownFieldError{Field: s.prefix + field})
+			return nil
+		}
+		if !s.sql && cfg.isAggregateCondition(field, val) {
+			return ks.unsupported("aggregate field")
+		}
+
+		column := processColumn(field, cfg)
+		var operator *Operator
+		if opName != "" {
+			operator = findOperatorByName(opName)
 		} else {
-			res := strings.Split(key, cfg.Separator)
-			column := processColumn(res[0], cfg)
-			var operator *Operator
-			if len(res) > 1 {
-				operator = findOperatorByName(res[1])
-			} else {
-				operator = findOperatorByValue(val)
-			}
+			// the implied operator is checked against the allowed operators too, e.g: "in" for a slice
+			operator = findOperatorByValue(val)
+			opName = operatorName(operator)
+		}
 
-			if operator == nil {
-				if cfg.Strict {
-					panic(&InvalidCond{cond: key, vars: val})
-				}
-				return
+		if operator == nil || (!ks.trusted && !cfg.isAllowedOperator(field, opName)) {
+			report(ReasonUnknownOperator, &UnknownOperatorError{Field: s.prefix + field, Operator: opName})
+			return nil
+		}
+
+		if operator.TimeValue || cfg.isTimeField(field) {
+			val = resolveRelativeTime(val, cfg.now(), operator.ranged)
+			val = convertTimes(val, cfg.Location)
+		}
+
+		if err := checkInline(val, cfg, ks.trusted, operator.ranged); err != nil {
+			if fe, ok := err.(*UnknownFieldError); ok {
+				report(ReasonUnknownField, &UnknownFieldError{Field: s.prefix + fe.Field})
+				return nil
 			}
+			verr = err
+		}
 
-			_sql, _vars = operator.Build(column, val, cfg)
+		if verr == nil && operator.Validate != nil {
+			verr = operator.Validate(val)
+		}
+		if verr != nil {
+			report(ReasonInvalidValue, &InvalidValueError{Field: s.prefix + field, Operator: opName, Value: val, Err: verr})
+			return nil
+		}
 
+		if sub, ok := toSubquery(val); ok {
+			if !s.sql {
+				return ks.unsupported("subquery")
+			}
+			sql, vars, err := operator.buildSubquery(column, sub, ks)
+			if err != nil {
+				report(ReasonInvalidValue, &InvalidValueError{Field: s.prefix + field, Operator: opName, Value: val, Err: err})
+				return nil
+			}
+			return &SQLNode{SQL: sql, Vars: vars}
+		}
+		if _, ok := val.(ExprValue); ok && !s.sql {
+			return ks.unsupported("expression")
 		}
 
-		if _sql != "" {
-			sqls = append(sqls, _sql)
-			vars = append(vars, _vars...)
+		return ks.render(s.renderer.Predicate(&Predicate{
+			Path:     ks.path,
+			Field:    field,
+			Column:   column,
+			Operator: operatorName(operator),
+			Op:       operator,
+			Value:    val,
+			Config:   cfg,
+		}))
+	}
+
+	// iterate the keys in sorted order so the same conditions always produce the same SQL
+	keys := make([]string, 0, vlen)
+	for k := range mc.value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// conditions on the same relation refer to the same related row, so they're grouped into one subquery.
+	// Except the "many" relations in NOT conditions, where each condition has its own subquery, just like Django's exclude()
+	groups := make(map[string]map[string]interface{})
+	for _, key := range keys {
+		name, subkey, ok := cfg.splitRelation(key)
+		if !ok || (mc.not && cfg.Relations[name].Many) {
+			continue
+		}
+		if groups[name] == nil {
+			groups[name] = make(map[string]interface{})
 		}
+		groups[name][subkey] = mc.value[key]
 	}
 
-	if cfg.sort {
-		keys := make([]string, 0, vlen)
-		for k := range mc.value {
-			keys = append(keys, k)
+	for _, key := range keys {
+		name, subkey, ok := cfg.splitRelation(key)
+		if !ok {
+			items = append(items, processFunc(key, mc.value[key]))
+			continue
 		}
-		sort.Strings(keys)
-		for _, key := range keys {
-			val := mc.value[key]
-			processFunc(key, val)
+		if s.clause == clauseHaving {
+			continue
 		}
-	} else {
-		for key, val := range mc.value {
-			processFunc(key, val)
+
+		conds := map[string]interface{}{subkey: mc.value[key]}
+		if group, grouped := groups[name]; grouped {
+			if group == nil {
+				// already built with the first key of the group
+				continue
+			}
+			conds = group
+			groups[name] = nil
 		}
-	}
 
-	sql := strings.Join(sqls, " AND ")
-	if sql != "" {
-		sql = "(" + sql + ")"
-		if mc.not {
-			sql = "NOT " + sql
+		if !s.sql {
+			items = append(items, s.at("."+s.prefix+key).unsupported("relation"))
+			continue
 		}
+		items = append(items, buildRelation(name, conds, s))
 	}
-	return sql, vars
+
+	return s.group(&Group{Not: mc.not}, items)
 }
 
-// listBuild is shared func for building andConditions & orConditions
-func listBuild(conds []interface{}, cfg *Config) ([]string, []interface{}) {
-	sqls := make([]string, 0, len(conds))
-	vars := make([]interface{}, 0)
+// listBuild is shared func for building andConditions & orConditions, returns the built items to be grouped
+func listBuild(conds []interface{}, s *buildState) []interface{} {
+	items := make([]interface{}, 0, len(conds))
 
 	for i := 0; i < len(conds); i++ {
-		var _sql string
-		var _vars []interface{}
+		var node interface{}
+		is := s.at(fmt.Sprintf("[%d]", i))
 
 		switch c := conds[i].(type) {
 		case map[string]interface{}:
 			mconds := &mapConditions{value: c}
-			_sql, _vars = mconds.build(cfg)
+			node = mconds.build(is)
 		case []interface{}:
 			if len(c) > 0 {
 				// test if it's in form of rawConditions
-				if len(c) >= 2 {
-					cl, cok := c[0].(string)
-					va := c[1:]
-					if cok {
-						sconds := &rawConditions{clause: cl, vars: va}
-						_sql, _vars = sconds.build(cfg)
-					}
-				}
-				// it's not a rawConditions, consider as orConditions
-				if _sql == "" {
+				cl, cok := c[0].(string)
+				if cok && len(c) >= 2 {
+					sconds := &rawConditions{clause: cl, vars: c[1:]}
+					node = sconds.build(is)
+				} else {
+					// it's not a rawConditions, consider as orConditions
 					oconds := &orConditions{value: c}
-					_sql, _vars = oconds.build(cfg)
+					node = oconds.build(is)
 				}
 			}
-		case *andConditions:
-			_sql, _vars = c.build(cfg)
-		case *orConditions:
-			_sql, _vars = c.build(cfg)
-		case *mapConditions:
-			_sql, _vars = c.build(cfg)
-		case *rawConditions:
-			_sql, _vars = c.build(cfg)
+		case condition:
+			node = c.build(is)
 		default:
-			if cfg.Strict {
-				panic(&InvalidCond{cond: c})
+			if s.clause == clauseHaving {
+				continue
 			}
+			is.report(&ConditionError{
+				Value:  c,
+				Reason: ReasonUnsupportedType,
+				Err:    &UnsupportedTypeError{Value: c},
+			})
 			continue
 		}
 
-		if _sql != "" {
-			sqls = append(sqls, _sql)
-			vars = append(vars, _vars...)
-		}
+		items = append(items, node)
 	}
 
-	return sqls, vars
+	return items
 }
 
 func processColumn(col string, cfg *Config) string {
+	if expr, ok := cfg.AggregateFields[col]; ok {
+		return expr
+	}
 	if alias, ok := cfg.ColumnAliases[col]; ok {
 		col = alias
 	}
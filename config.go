@@ -1,5 +1,10 @@
 package gowhere
 
+import (
+	"context"
+	"time"
+)
+
 // Config defines the config for planner
 type Config struct {
 	// The separator between field and operation. Default to "__" which requires the condition format as: field__operator
@@ -15,9 +20,43 @@ type Config struct {
 	ColumnAliases map[string]string
 	// Custom conditions allow full access on the condition generating
 	CustomConditions map[string]CustomConditionFn
+	// The map of fields which are allowed in the conditions, to the list of allowed operators. Empty list allows all operators.
+	// Default to nil which allows all fields. Custom conditions are always allowed.
+	// Example: {"name": {"exact", "icontains"}, "budget": nil}
+	AllowedFields map[string][]string
+	// The relations to other tables, which allow the conditions on the fields of the related tables, e.g: "author__name".
+	// Example: {"author": {Table: "authors"}, "comments": {Table: "comments", ForeignKey: "trip_id", Many: true}}
+	Relations map[string]Relation
+	// The fields to resolve relative time values, e.g: "now-7d", with any operators. Operators for time values such as
+	// date, between & datebetween always resolve the relative time values
+	TimeFields []string
+	// The clock to resolve relative time values. Default to time.Now
+	Now func() time.Time
+	// The time zone of the time values, e.g: the time zone of the user. The given times are converted to this location,
+	// strings in RFC3339 format are parsed with their offsets, and relative time values are resolved in this location.
+	// Default to nil which keeps the times unchanged
+	Location *time.Location
+	// Whether to convert the column to the Location in SQL for the time values, e.g: DATE("created_at" AT TIME ZONE ?),
+	// so the date of the column is in the same time zone as the given values. Requires a timestamp with time zone
+	// column in PostgreSQL, or a column in UTC in MySQL
+	ConvertTimeZone bool
+	// Whether to render the date operators (date, datebetween, year) as half-open ranges: column >= ? AND column < ?,
+	// which can use the index of the column, instead of DATE(column) or EXTRACT(YEAR FROM column). Default to false
+	SargableDates bool
+	// The map of aggregate fields to their SQL expressions, e.g: {"trip_count": "COUNT(trips.id)"}. The conditions on these
+	// fields are built into the HAVING clause, see Plan.HavingSQL(). Default to nil which builds all conditions into WHERE
+	AggregateFields map[string]string
+	// The renderer of the conditions for Build, which must render *SQLNode, e.g: to emit an alternative SQL style for some
+	// operators. Default to nil which uses SQLRenderer. See Plan.Render for the other backends
+	Renderer Renderer
+	// Whether Exec, and Statement for the UPDATE & DELETE statements, run without the WHERE clause if the plan has no
+	// conditions, i.e: affect the whole table. Default to false which returns ErrEmptyPlan
+	AllowEmptyExec bool
 
-	// sort the conditions in map by field for testing purposes only
-	sort bool
+	// the context of the current build, see Config.Context()
+	ctx context.Context
+	// whether the column is converted to the Location, so the time values are bound without the offset
+	naiveTimes bool
 }
 
 var (
@@ -38,3 +77,49 @@ const (
 	AppendMode    = 'a'
 	WriteMode     = 'w'
 )
+
+// isAllowedField reports whether the field is in the allowed fields
+func (c *Config) isAllowedField(field string) bool {
+	if c.AllowedFields == nil {
+		return true
+	}
+	_, ok := c.AllowedFields[field]
+	return ok
+}
+
+// isAllowedOperator reports whether the operator is allowed for the field. The operator implied by the value is given
+// by its name, e.g: "exact", "in" or "isnull"
+func (c *Config) isAllowedOperator(field, operator string) bool {
+	ops := c.AllowedFields[field]
+	if len(ops) == 0 {
+		return true
+	}
+	for _, op := range ops {
+		if op == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// now returns the current time by the configured clock, in the configured location if any
+func (c *Config) now() time.Time {
+	now := time.Now()
+	if c.Now != nil {
+		now = c.Now()
+	}
+	if c.Location != nil {
+		now = now.In(c.Location)
+	}
+	return now
+}
+
+// isTimeField reports whether the field is in the time fields
+func (c *Config) isTimeField(field string) bool {
+	for _, f := range c.TimeFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package gowhere
+
+import "context"
+
+// ContextValue represents a placeholder value which is resolved from the context at build time
+type ContextValue struct {
+	Key interface{}
+}
+
+// FromContext returns a placeholder value which is replaced by ctx.Value(key) when the plan is built with BuildContext.
+// It allows to share the same conditions between requests, e.g: {"user_id": gowhere.FromContext(userIDKey)}
+func FromContext(key interface{}) ContextValue {
+	return ContextValue{Key: key}
+}
+
+// Context returns the context given to Plan.BuildContext, or context.Background() if not given.
+// Custom conditions and operators can use it to access request-scoped values, such as the current user.
+func (c *Config) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
@@ -0,0 +1,126 @@
+package gowhere
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type ctxKey string
+
+func TestPlan_BuildContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey("user_id"), 42)
+	ctx = context.WithValue(ctx, ctxKey("locale"), "vi")
+
+	cfg := Config{
+		Strict: true,
+		CustomConditions: map[string]CustomConditionFn{
+			"mine": func(key string, val interface{}, cfg *Config) interface{} {
+				if mine, _ := val.(bool); !mine {
+					return nil
+				}
+				return map[string]interface{}{"owner_id": cfg.Context().Value(ctxKey("user_id"))}
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		cond     interface{}
+		vars     []interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "custom condition",
+			cond:     map[string]interface{}{"mine": true},
+			wantSQL:  `(("owner_id" = ?))`,
+			wantVars: []interface{}{42},
+		},
+		{
+			name:     "context value",
+			cond:     map[string]interface{}{"locale": FromContext(ctxKey("locale")), "name": "Go"},
+			wantSQL:  `("locale" = ? AND "name" = ?)`,
+			wantVars: []interface{}{"vi", "Go"},
+		},
+		{
+			name:     "context value in slice",
+			cond:     map[string]interface{}{"owner_id__in": []interface{}{1, FromContext(ctxKey("user_id"))}},
+			wantSQL:  `("owner_id" IN (?))`,
+			wantVars: []interface{}{[]interface{}{1, 42}},
+		},
+		{
+			name:     "context value in raw condition",
+			cond:     "owner_id = ?",
+			vars:     []interface{}{FromContext(ctxKey("user_id"))},
+			wantSQL:  `(owner_id = ?)`,
+			wantVars: []interface{}{42},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := WithConfig(cfg).Where(tt.cond, tt.vars...).BuildContext(ctx)
+			if plan.Error != nil {
+				t.Fatalf("unexpected error: %+v", plan.Error)
+			}
+			if sql := plan.SQL(); sql != tt.wantSQL {
+				t.Errorf("sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if vars := plan.Vars(); !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+
+	// missing value in context
+	plan := WithConfig(cfg).Where(map[string]interface{}{"owner_id": FromContext(ctxKey("user_id"))}).Build()
+	if !errors.Is(plan.Error, ErrInvalidValue) {
+		t.Errorf("Error = %v, want %v", plan.Error, ErrInvalidValue)
+	}
+
+	// the changed plan is rebuilt with the same context
+	plan = WithConfig(cfg).Where(map[string]interface{}{"owner_id": FromContext(ctxKey("user_id"))}).BuildContext(ctx)
+	plan.SetTable("trips")
+	if sql, vars := plan.SQL(), plan.Vars(); plan.Error != nil || sql != `("trips"."owner_id" = ?)` || !reflect.DeepEqual(vars, []interface{}{42}) {
+		t.Errorf("sql = %v, vars = %v, error = %v, want the value from context", sql, vars, plan.Error)
+	}
+
+	// missing value fails the plan closed, even if not strict
+	for _, plan := range []*Plan{
+		Where(map[string]interface{}{"owner_id": FromContext(ctxKey("user_id")), "name": "Go"}).Build(),
+		Where("owner_id = ?", FromContext(ctxKey("user_id"))).Build(),
+	} {
+		if !errors.Is(plan.Error, ErrInvalidValue) && !errors.Is(plan.Error, ErrInvalidRaw) {
+			t.Errorf("Error = %v, want an error", plan.Error)
+		}
+		if sql, vars := plan.SQL(), plan.Vars(); sql != "(1 = 0)" || len(vars) != 0 {
+			t.Errorf("sql = %v, vars = %v, want (1 = 0)", sql, vars)
+		}
+	}
+}
+
+func TestPlan_MatchContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey("tenant_id"), 7)
+	rows := []map[string]interface{}{{"tenant_id": 7, "name": "Go"}, {"tenant_id": 99, "name": "Go"}}
+
+	// the scopes are resolved from the context of the build
+	plan := Where(map[string]interface{}{"name": "Go"}).Scope(map[string]interface{}{"tenant_id": FromContext(ctxKey("tenant_id"))})
+	plan.BuildContext(ctx)
+	if ok, err := plan.Match(rows[1]); ok || err != nil {
+		t.Errorf("Plan.Match() = %v, %v, want false", ok, err)
+	}
+	got, err := plan.Filter(rows)
+	if want := rows[:1]; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Plan.Filter() = %v, %v, want %v", got, err, want)
+	}
+
+	// a missing value never skips the scope
+	plan.Build()
+	if ok, err := plan.Match(rows[1]); ok || !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Plan.Match() = %v, %v, want %v", ok, err, ErrInvalidValue)
+	}
+	if _, err := plan.Filter(rows); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Plan.Filter() error = %v, want %v", err, ErrInvalidValue)
+	}
+}
@@ -0,0 +1,222 @@
+package gowhere
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned by DecodeCursor if the token is malformed or has been tampered with
+var ErrInvalidCursor = errors.New("gowhere: invalid cursor")
+
+// RowComparer is the optional interface for a dialect which supports the row comparison, e.g: (a, b) > (?, ?).
+// The dialects without it use the expanded form: a > ? OR (a = ? AND b > ?)
+type RowComparer interface {
+	SupportsRowComparison() bool
+}
+
+// keyset represents the cursor for keyset pagination
+type keyset struct {
+	values interface{}
+	before bool
+}
+
+// After adds the keyset pagination condition to fetch the rows after the cursor, according to the sorting of the plan.
+// The cursor is either the values of the sorting fields of the last row in order, i.e []interface{},
+// or a map of the sorting fields to the values, i.e map[string]interface{}.
+// Note: the sorting fields should be non-null, and unique as a whole, e.g: add the primary key as the last one.
+func (p *Plan) After(cursor interface{}) *Plan {
+	p.cursor = &keyset{values: cursor}
+	p.built = false
+	return p
+}
+
+// Before works like After, but fetches the rows before the cursor. The directions of OrderSQL are reversed, so the
+// rows nearest to the cursor come first, which should be reversed by the caller to keep the original order.
+func (p *Plan) Before(cursor interface{}) *Plan {
+	p.cursor = &keyset{values: cursor, before: true}
+	p.built = false
+	return p
+}
+
+// build renders the keyset predicate of the given sorts
+func (k *keyset) build(sorts []Sort, s *buildState) (string, []interface{}) {
+	cs := s.at("cursor")
+	values, err := k.valuesOf(sorts)
+	if err != nil {
+		cs.report(&ConditionError{Value: k.values, Reason: ReasonInvalidValue, Err: &InvalidValueError{Value: k.values, Err: err}})
+		return "", []interface{}{}
+	}
+
+	cfg := s.cfg
+	columns := make([]string, len(sorts))
+	operators := make([]string, len(sorts))
+	sameDirection := true
+	for i, sort := range sorts {
+		columns[i] = processColumn(sort.Field, cfg)
+		// ascending after the cursor, or descending before the cursor
+		operators[i] = ">"
+		if sort.Desc != k.before {
+			operators[i] = "<"
+		}
+		sameDirection = sameDirection && operators[i] == operators[0]
+	}
+
+	if rc, ok := cfg.Dialect.(RowComparer); ok && rc.SupportsRowComparison() && sameDirection && len(sorts) > 1 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		return fmt.Sprintf("((%s) %s (%s))", strings.Join(columns, ", "), operators[0], placeholders), values
+	}
+
+	// a > ? OR (a = ? AND b > ?) OR (a = ? AND b = ? AND c > ?)
+	ors := make([]string, len(sorts))
+	vars := make([]interface{}, 0)
+	for i := range sorts {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, columns[j]+" = ?")
+			vars = append(vars, values[j])
+		}
+		ands = append(ands, columns[i]+" "+operators[i]+" ?")
+		vars = append(vars, values[i])
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", vars
+}
+
+// valuesOf returns the values of the cursor in the order of the sorts
+func (k *keyset) valuesOf(sorts []Sort) ([]interface{}, error) {
+	if len(sorts) == 0 {
+		return nil, errors.New("cursor requires the sorting fields")
+	}
+
+	values := make([]interface{}, len(sorts))
+	switch v := k.values.(type) {
+	case []interface{}:
+		if len(v) != len(sorts) {
+			return nil, fmt.Errorf("cursor has %d value(s) but %d sorting field(s)", len(v), len(sorts))
+		}
+		for i, val := range v {
+			values[i] = cursorVar(val)
+		}
+	case map[string]interface{}:
+		for i, sort := range sorts {
+			val, ok := v[sort.Field]
+			if !ok {
+				return nil, fmt.Errorf("cursor has no value of the sorting field %q", sort.Field)
+			}
+			values[i] = cursorVar(val)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported cursor type %T", k.values)
+	}
+	return values, nil
+}
+
+// cursorVar returns the var of the cursor value. The times are bound as time.Time to keep the fractional seconds &
+// the offset, which must match the values of the last row exactly
+func cursorVar(val interface{}) interface{} {
+	switch t := val.(type) {
+	case time.Time:
+		return t
+	case *time.Time:
+		if t != nil {
+			return *t
+		}
+	}
+	return Utils.ToSQLVar(val)
+}
+
+// reverseSorts returns the sorts in the opposite directions
+func reverseSorts(sorts []Sort) []Sort {
+	reversed := make([]Sort, len(sorts))
+	for i, sort := range sorts {
+		sort.Desc = !sort.Desc
+		switch sort.Nulls {
+		case NullsFirst:
+			sort.Nulls = NullsLast
+		case NullsLast:
+			sort.Nulls = NullsFirst
+		}
+		reversed[i] = sort
+	}
+	return reversed
+}
+
+// EncodeCursor encodes the values of the last row to an opaque token, signed by the secret to be tamper-evident
+func EncodeCursor(values []interface{}, secret []byte) (string, error) {
+	payload, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+// DecodeCursor verifies & decodes the token created by EncodeCursor. The values are decoded from JSON, with the
+// integer numbers as int64 and the others as float64. Returns ErrInvalidCursor if the token is invalid
+func DecodeCursor(token string, secret []byte) ([]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sum, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var values []interface{}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	for i, val := range values {
+		if num, ok := val.(json.Number); ok {
+			if n, err := num.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := num.Float64(); err == nil {
+				values[i] = f
+			}
+		}
+	}
+	return values, nil
+}
+
+// buildCursor ties the keyset predicate with the built conditions by AND operator, so it can't be escaped by Or.
+// The ORDER BY clause is reversed for Before
+func (p *Plan) buildCursor(s *buildState) {
+	sql, vars := p.cursor.build(p.sorts, s)
+	if sql == "" {
+		return
+	}
+	if p.sql != "" {
+		sql = p.sql + " AND " + sql
+	}
+	p.sql, p.vars = sql, append(p.vars, vars...)
+
+	if p.cursor.before {
+		items := make([]string, 0, len(p.sorts))
+		for _, sort := range reverseSorts(p.sorts) {
+			items = append(items, orderItem(sort, s.cfg))
+		}
+		p.orderSQL = "ORDER BY " + strings.Join(items, ", ")
+	}
+}
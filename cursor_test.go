@@ -0,0 +1,141 @@
+package gowhere
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlan_After(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		plan      func(p *Plan) *Plan
+		wantSQL   string
+		wantVars  []interface{}
+		wantOrder string
+	}{
+		{
+			name: "expanded form",
+			plan: func(p *Plan) *Plan {
+				return p.Where(map[string]interface{}{"a": 1}).Or(map[string]interface{}{"b": 2}).
+					OrderBy("-created_at,id").After([]interface{}{"2020-01-01", 5})
+			},
+			wantSQL:   `((("a" = ?)) OR ("b" = ?)) AND (("created_at" < ?) OR ("created_at" = ? AND "id" > ?))`,
+			wantVars:  []interface{}{1, 2, "2020-01-01", "2020-01-01", 5},
+			wantOrder: `ORDER BY "created_at" DESC, "id" ASC`,
+		},
+		{
+			name: "sub-second times",
+			plan: func(p *Plan) *Plan {
+				return p.OrderBy("-created_at,id").After([]interface{}{time.Date(2020, 1, 1, 10, 0, 0, 5e8, time.FixedZone("", 7*3600)), 5})
+			},
+			wantSQL: `(("created_at" < ?) OR ("created_at" = ? AND "id" > ?))`,
+			wantVars: []interface{}{time.Date(2020, 1, 1, 10, 0, 0, 5e8, time.FixedZone("", 7*3600)),
+				time.Date(2020, 1, 1, 10, 0, 0, 5e8, time.FixedZone("", 7*3600)), 5},
+			wantOrder: `ORDER BY "created_at" DESC, "id" ASC`,
+		},
+		{
+			name: "row comparison",
+			cfg:  Config{Dialect: DialectPostgreSQL, Table: "trips"},
+			plan: func(p *Plan) *Plan {
+				return p.OrderBy("name,id").After(map[string]interface{}{"id": 5, "name": "x"})
+			},
+			wantSQL:   `(("trips"."name", "trips"."id") > (?, ?))`,
+			wantVars:  []interface{}{"x", 5},
+			wantOrder: `ORDER BY "trips"."name" ASC, "trips"."id" ASC`,
+		},
+		{
+			name: "mysql lacks row comparison",
+			cfg:  Config{Dialect: DialectMySQL},
+			plan: func(p *Plan) *Plan {
+				return p.OrderBy("name,id").After([]interface{}{"x", 5})
+			},
+			wantSQL:   "((`name` > ?) OR (`name` = ? AND `id` > ?))",
+			wantVars:  []interface{}{"x", "x", 5},
+			wantOrder: "ORDER BY `name` ASC, `id` ASC",
+		},
+		{
+			name: "before reverses the order",
+			plan: func(p *Plan) *Plan {
+				return p.OrderBy("-paid_at:nulls_last,id").Before([]interface{}{"2020-01-01", 5})
+			},
+			wantSQL:   `(("paid_at" > ?) OR ("paid_at" = ? AND "id" < ?))`,
+			wantVars:  []interface{}{"2020-01-01", "2020-01-01", 5},
+			wantOrder: `ORDER BY "paid_at" ASC NULLS FIRST, "id" DESC`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Strict = true
+			plan := tt.plan(WithConfig(tt.cfg)).Build()
+			if plan.Error != nil {
+				t.Fatalf("unexpected error: %+v", plan.Error)
+			}
+			if got := plan.SQL(); got != tt.wantSQL {
+				t.Errorf("Plan.SQL() = %v, want %v", got, tt.wantSQL)
+			}
+			if got := plan.Vars(); !reflect.DeepEqual(got, tt.wantVars) {
+				t.Errorf("Plan.Vars() = %v, want %v", got, tt.wantVars)
+			}
+			if got := plan.OrderSQL(); got != tt.wantOrder {
+				t.Errorf("Plan.OrderSQL() = %v, want %v", got, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestPlan_After_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  interface{}
+		cursor interface{}
+	}{
+		{name: "no sorting", cursor: []interface{}{1}},
+		{name: "mismatched values", order: "id", cursor: []interface{}{1, 2}},
+		{name: "missing field", order: "name,id", cursor: map[string]interface{}{"id": 1}},
+		{name: "unsupported type", order: "id", cursor: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Where(map[string]interface{}{"a": 1})
+			if tt.order != nil {
+				plan.OrderBy(tt.order)
+			}
+			plan.After(tt.cursor).Build()
+			if got := plan.SQL(); got != `("a" = ?)` {
+				t.Errorf("Plan.SQL() = %v, want the cursor skipped", got)
+			}
+			warnings := plan.Warnings()
+			if len(warnings) != 1 || warnings[0].Path != "cursor" || !errors.Is(warnings[0], ErrInvalidValue) {
+				t.Errorf("Plan.Warnings() = %v, want an invalid cursor", warnings)
+			}
+		})
+	}
+}
+
+func TestCursorToken(t *testing.T) {
+	secret := []byte("secret")
+	token, err := EncodeCursor([]interface{}{"2020-01-01", 5, 1.5}, secret)
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+
+	values, err := DecodeCursor(token, secret)
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if want := []interface{}{"2020-01-01", int64(5), 1.5}; !reflect.DeepEqual(values, want) {
+		t.Errorf("DecodeCursor() = %v, want %v", values, want)
+	}
+
+	for _, invalid := range []string{"", "abc", token + "x", "e30." + token[len(token)-43:]} {
+		if _, err := DecodeCursor(invalid, secret); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", invalid, err)
+		}
+	}
+	if _, err := DecodeCursor(token, []byte("other")); err != ErrInvalidCursor {
+		t.Errorf("DecodeCursor() with other secret error = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -1,6 +1,10 @@
 package gowhere
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Dialect represents the interface for a dialect
 type Dialect interface {
@@ -8,6 +12,36 @@ type Dialect interface {
 	QuoteIdentifier(string) string
 }
 
+// TimeZoneConverter is the optional interface for a dialect which can convert a column to another time zone in SQL
+type TimeZoneConverter interface {
+	// ConvertTimeZone returns the SQL to convert the column, with a "?" placeholder for the name of the time zone
+	ConvertTimeZone(column string) string
+}
+
+// timeZoneName returns the IANA name of the location, or its UTC offset at the given time, e.g: "+07:00",
+// if the name is unknown, e.g: time.Local or a fixed zone
+func timeZoneName(loc *time.Location, now time.Time) string {
+	if name := loc.String(); name != "Local" && name != "" {
+		if _, err := time.LoadLocation(name); err == nil {
+			return name
+		}
+	}
+	return now.In(loc).Format("-07:00")
+}
+
+// sqlTimeZone returns the time zone of the Location config for TimeZoneConverter. PostgreSQL reads a text offset as
+// a POSIX time zone, which is positive to the west of UTC, so "+07:00" is given as "UTC-07:00"
+func sqlTimeZone(cfg *Config) string {
+	name := timeZoneName(cfg.Location, cfg.now())
+	if cfg.Dialect.GetName() == DialectPostgreSQLName && (name[0] == '+' || name[0] == '-') {
+		if name[0] == '+' {
+			return "UTC-" + name[1:]
+		}
+		return "UTC+" + name[1:]
+	}
+	return name
+}
+
 type mysqlDialect struct{}
 type postgresqlDialect struct{}
 
@@ -38,6 +72,20 @@ func (md *mysqlDialect) QuoteIdentifier(name string) string {
 	return strings.Replace(name, ".", "`.`", -1)
 }
 
+// ConvertTimeZone assumes the column is stored in UTC
+func (md *mysqlDialect) ConvertTimeZone(column string) string {
+	return "CONVERT_TZ(" + column + ", '+00:00', ?)"
+}
+
+// OrderNulls emulates NULLS FIRST/LAST, which is not supported by MySQL
+func (md *mysqlDialect) OrderNulls(column, direction string, nullsFirst bool) string {
+	nulls := "ASC"
+	if nullsFirst {
+		nulls = "DESC"
+	}
+	return column + " IS NULL " + nulls + ", " + column + " " + direction
+}
+
 func (pd *postgresqlDialect) GetName() string {
 	return DialectPostgreSQLName
 }
@@ -50,3 +98,18 @@ func (pd *postgresqlDialect) QuoteIdentifier(name string) string {
 	name = `"` + strings.Replace(name, `"`, `""`, -1) + `"`
 	return strings.Replace(name, `.`, `"."`, -1)
 }
+
+// ConvertTimeZone assumes the column is a timestamp with time zone
+func (pd *postgresqlDialect) ConvertTimeZone(column string) string {
+	return "(" + column + " AT TIME ZONE ?)"
+}
+
+// SupportsRowComparison returns true, PostgreSQL can use the index for the row comparison
+func (pd *postgresqlDialect) SupportsRowComparison() bool {
+	return true
+}
+
+// Placeholder returns the numbered placeholder: $1, $2, ...
+func (pd *postgresqlDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
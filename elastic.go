@@ -0,0 +1,140 @@
+package gowhere
+
+import (
+	"strconv"
+	"strings"
+)
+
+// elasticRenderer renders the conditions to Elasticsearch bool queries
+type elasticRenderer struct{}
+
+// ToElastic returns the Elasticsearch query of the conditions & scopes, e.g: {"bool": {"must": [...]}}, as plain maps
+// ready to be marshaled as the "query" of a search request. The fields are mapped by the column aliases to the index
+// fields, e.g: {"name": "name.keyword"} for the exact matching of a text field.
+// Like Build, the invalid conditions are skipped in non-strict mode, and Plan.Error is returned otherwise.
+// The values created by FromContext are resolved from the context of the last BuildContext, see Render.
+// Raw conditions, subqueries, relations, expressions, column references, aggregate fields & custom operators
+// return ErrNotRenderable. Returns the match_all query if there are no conditions
+func (p *Plan) ToElastic() (map[string]interface{}, error) {
+	query, err := p.Render(elasticRenderer{})
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return map[string]interface{}{"match_all": map[string]interface{}{}}, nil
+	}
+	return query.(map[string]interface{}), nil
+}
+
+func (elasticRenderer) target() string {
+	return "Elasticsearch"
+}
+
+func (r elasticRenderer) Group(g *Group) (interface{}, error) {
+	return tieDocs(g, r.and, r.or, r.not), nil
+}
+
+func (r elasticRenderer) Raw(*Raw) (interface{}, error) {
+	return nil, &NotRenderableError{Condition: "raw condition", Target: r.target()}
+}
+
+func (elasticRenderer) and(docs []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"bool": map[string]interface{}{"must": docs}}
+}
+
+func (elasticRenderer) or(docs []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"bool": map[string]interface{}{"should": docs, "minimum_should_match": 1}}
+}
+
+func (elasticRenderer) not(doc map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"bool": map[string]interface{}{"must_not": []map[string]interface{}{doc}}}
+}
+
+func (r elasticRenderer) Predicate(pred *Predicate) (interface{}, error) {
+	cfg := pred.Config
+	field := pred.Field
+	if alias, ok := cfg.ColumnAliases[field]; ok {
+		field = alias
+	}
+	query := func(kind string, cond interface{}) map[string]interface{} {
+		return map[string]interface{}{kind: map[string]interface{}{field: cond}}
+	}
+	// the term level queries with the case insensitive option, available since Elasticsearch 7.10
+	term := func(kind string, value interface{}, insensitive bool) map[string]interface{} {
+		cond := map[string]interface{}{"value": value}
+		if insensitive {
+			cond["case_insensitive"] = true
+		}
+		return query(kind, cond)
+	}
+	// the upper bound is "lte" for the inclusive ranges, "lt" for the half-open ones
+	dateRange := func(format string, from interface{}, upper string, to interface{}) map[string]interface{} {
+		cond := map[string]interface{}{"gte": from, upper: to, "format": format}
+		if cfg.Location != nil {
+			cond["time_zone"] = timeZoneName(cfg.Location, cfg.now())
+		}
+		return query("range", cond)
+	}
+
+	unsupported := func(what string) (interface{}, error) {
+		return nil, &NotRenderableError{Condition: what, Target: r.target()}
+	}
+
+	name := pred.Operator
+	value := pred.Value
+	if _, ok := value.(ColumnValue); ok {
+		return unsupported("column reference")
+	}
+
+	wildcard := escapeWildcard(Utils.ToString(value))
+	switch name {
+	case "exact":
+		return query("term", value), nil
+	case "notexact":
+		return r.not(query("term", value)), nil
+	case "iexact":
+		return term("term", value, true), nil
+	case "inotexact":
+		return r.not(term("term", value, true)), nil
+	case "gt", "gte", "lt", "lte":
+		return query("range", map[string]interface{}{name: value}), nil
+	case "startswith", "istartswith":
+		return term("prefix", Utils.ToString(value), name == "istartswith"), nil
+	case "endswith", "iendswith":
+		return term("wildcard", "*"+wildcard, name == "iendswith"), nil
+	case "contains", "icontains":
+		return term("wildcard", "*"+wildcard+"*", name == "icontains"), nil
+	case "in":
+		return query("terms", Utils.ToSlice(value)), nil
+	case "notin":
+		return r.not(query("terms", Utils.ToSlice(value))), nil
+	case "isnull":
+		exists := map[string]interface{}{"exists": map[string]interface{}{"field": field}}
+		if null, ok := value.(bool); ok && !null {
+			return exists, nil
+		}
+		return r.not(exists), nil
+	case "between":
+		from, to, _ := rangeValues(value)
+		return query("range", map[string]interface{}{"gte": from, "lte": to}), nil
+	case "date":
+		// the upper bound is rounded up to the end of the day
+		date := Utils.ToDate(value)
+		return dateRange("yyyy-MM-dd", date, "lte", date), nil
+	case "year":
+		year, err := strconv.Atoi(Utils.ToString(value))
+		if err != nil {
+			return nil, &ConditionError{Reason: ReasonInvalidValue, Err: &InvalidValueError{Field: pred.Field, Operator: name, Value: value, Err: err}}
+		}
+		return dateRange("yyyy", strconv.Itoa(year), "lt", strconv.Itoa(year+1)), nil
+	case "datebetween":
+		from, to, _ := rangeValues(value)
+		return dateRange("yyyy-MM-dd", Utils.ToDate(from), "lte", Utils.ToDate(to)), nil
+	}
+	return unsupported("operator " + name)
+}
+
+// escapeWildcard escapes the special characters of the wildcard query: "*", "?" and "\"
+func escapeWildcard(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`).Replace(value)
+}
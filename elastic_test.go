@@ -0,0 +1,78 @@
+package gowhere
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPlan_ToElastic(t *testing.T) {
+	tests := []struct {
+		name string
+		plan *Plan
+		want string
+	}{
+		{
+			name: "operators",
+			plan: WithConfig(Config{ColumnAliases: map[string]string{"name": "name.keyword"}}).Where(map[string]interface{}{
+				"name__icontains": "go*",
+				"budget__gte":     100,
+				"id":              []int{1, 2},
+				"deleted_at":      nil,
+			}),
+			want: `{"bool":{"must":[{"range":{"budget":{"gte":100}}},{"bool":{"must_not":[{"exists":{"field":"deleted_at"}}]}},` +
+				`{"terms":{"id":[1,2]}},{"wildcard":{"name.keyword":{"case_insensitive":true,"value":"*go\\**"}}}]}}`,
+		},
+		{
+			name: "or & not",
+			plan: Where(map[string]interface{}{"id": 1}).Or(map[string]interface{}{"name__startswith": "a"}).
+				Not(map[string]interface{}{"tag__notin": []string{"x"}}),
+			want: `{"bool":{"must":[{"bool":{"minimum_should_match":1,"should":[{"term":{"id":1}},{"prefix":{"name":{"value":"a"}}}]}},` +
+				`{"bool":{"must_not":[{"bool":{"must_not":[{"terms":{"tag":["x"]}}]}}]}}]}}`,
+		},
+		{
+			name: "dates in location",
+			plan: WithConfig(Config{Location: time.FixedZone("", 7*3600)}).Where(map[string]interface{}{"created_at__datebetween": []string{"2020-03-01", "2020-03-15"}}),
+			want: `{"range":{"created_at":{"format":"yyyy-MM-dd","gte":"2020-03-01","lte":"2020-03-15","time_zone":"+07:00"}}}`,
+		},
+		{
+			name: "year & scope",
+			plan: Where(map[string]interface{}{"created_at__year": 2020}).Scope(map[string]interface{}{"tenant_id": 1}),
+			want: `{"bool":{"must":[{"term":{"tenant_id":1}},{"range":{"created_at":{"format":"yyyy","gte":"2020","lt":"2021"}}}]}}`,
+		},
+		{
+			name: "context scope",
+			plan: Where(map[string]interface{}{"id": 1}).Scope(map[string]interface{}{"tenant_id": FromContext(ctxKey("tenant_id"))}).
+				BuildContext(context.WithValue(context.Background(), ctxKey("tenant_id"), 7)),
+			want: `{"bool":{"must":[{"term":{"tenant_id":7}},{"term":{"id":1}}]}}`,
+		},
+		{
+			name: "empty",
+			plan: Where(map[string]interface{}{}),
+			want: `{"match_all":{}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.plan.ToElastic()
+			if err != nil {
+				t.Fatalf("Plan.ToElastic() error = %v", err)
+			}
+			b, _ := json.Marshal(got)
+			if string(b) != tt.want {
+				t.Errorf("Plan.ToElastic() = %s, want %s", b, tt.want)
+			}
+		})
+	}
+
+	if _, err := Where(map[string]interface{}{"spent__gt": Col("budget")}).ToElastic(); !errors.Is(err, ErrNotRenderable) {
+		t.Errorf("Plan.ToElastic() error = %v, want ErrNotRenderable", err)
+	}
+	// a missing context value never skips the scope
+	scoped := Where(map[string]interface{}{"id": 1}).Scope(map[string]interface{}{"tenant_id": FromContext(ctxKey("tenant_id"))})
+	if _, err := scoped.ToElastic(); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Plan.ToElastic() error = %v, want ErrInvalidValue", err)
+	}
+}
@@ -1,10 +1,47 @@
 package gowhere
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+)
+
+// Reason codes of ConditionError
+const (
+	// ReasonUnknownField means the field is not in the allowed fields
+	ReasonUnknownField = "unknown_field"
+	// ReasonUnknownOperator means the operator suffix of the condition key is not registered or not allowed
+	ReasonUnknownOperator = "unknown_operator"
+	// ReasonInvalidValue means the value doesn't fit the operator, e.g: between without two items
+	ReasonInvalidValue = "invalid_value"
+	// ReasonUnsupportedType means the condition is neither a map, a slice nor a raw SQL string
+	ReasonUnsupportedType = "unsupported_type"
+	// ReasonInvalidRaw means the raw SQL condition is malformed
+	ReasonInvalidRaw = "invalid_raw"
+	// ReasonMixedClauses means an OR or NOT group has both aggregate and non-aggregate fields, which can't be split
+	// into the WHERE and HAVING clauses
+	ReasonMixedClauses = "mixed_clauses"
+	// ReasonNotEvaluable means the condition can't be evaluated in memory by Plan.Match, e.g: a raw SQL condition
+	ReasonNotEvaluable = "not_evaluable"
+	// ReasonNotRenderable means the condition can't be rendered by the renderer, e.g: a raw condition for MongoDB
+	ReasonNotRenderable = "not_renderable"
+)
+
+// Sentinel errors to match the problems with errors.Is
+var (
+	ErrUnknownField    = errors.New("gowhere: unknown field")
+	ErrUnknownOperator = errors.New("gowhere: unknown operator")
+	ErrInvalidValue    = errors.New("gowhere: invalid value")
+	ErrUnsupportedType = errors.New("gowhere: unsupported condition type")
+	ErrInvalidRaw      = errors.New("gowhere: invalid raw condition")
+	ErrMixedClauses    = errors.New("gowhere: mixed aggregate and non-aggregate fields in OR/NOT group")
+	ErrNotEvaluable    = errors.New("gowhere: condition can't be evaluated in memory")
+	ErrNotRenderable   = errors.New("gowhere: condition can't be rendered for the target")
 )
 
 // InvalidCond represents the error when invalid condition is given
+//
+// Deprecated: problems are reported by the typed errors below, such as UnknownOperatorError or UnsupportedTypeError.
 type InvalidCond struct {
 	// The given condition(s)
 	cond interface{}
@@ -15,3 +52,162 @@ type InvalidCond struct {
 func (e *InvalidCond) Error() string {
 	return fmt.Sprintf("Invalid Conditions: %+v %+v", e.cond, e.vars)
 }
+
+// UnknownFieldError represents the error when the field is not in the allowed fields
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
+
+// Is reports whether the target is ErrUnknownField
+func (e *UnknownFieldError) Is(target error) bool {
+	return target == ErrUnknownField
+}
+
+// UnknownOperatorError represents the error when the operator is not registered or not allowed for the field
+type UnknownOperatorError struct {
+	Field    string
+	Operator string
+}
+
+func (e *UnknownOperatorError) Error() string {
+	return fmt.Sprintf("unknown operator %q of field %q", e.Operator, e.Field)
+}
+
+// Is reports whether the target is ErrUnknownOperator
+func (e *UnknownOperatorError) Is(target error) bool {
+	return target == ErrUnknownOperator
+}
+
+// InvalidValueError represents the error when the value doesn't fit the operator
+type InvalidValueError struct {
+	Field    string
+	Operator string
+	Value    interface{}
+	// The reason why the value is invalid
+	Err error
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value %+v of field %q for operator %q: %v", e.Value, e.Field, e.Operator, e.Err)
+}
+
+// Is reports whether the target is ErrInvalidValue
+func (e *InvalidValueError) Is(target error) bool {
+	return target == ErrInvalidValue
+}
+
+// Unwrap returns the reason why the value is invalid
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedTypeError represents the error when the given condition is neither a map, a slice nor a raw SQL string
+type UnsupportedTypeError struct {
+	Value interface{}
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported condition type %T: %+v", e.Value, e.Value)
+}
+
+// Is reports whether the target is ErrUnsupportedType
+func (e *UnsupportedTypeError) Is(target error) bool {
+	return target == ErrUnsupportedType
+}
+
+// InvalidRawError represents the error when the raw SQL condition is malformed
+type InvalidRawError struct {
+	Clause string
+	Vars   []interface{}
+	// The reason why the raw condition is malformed
+	Reason string
+}
+
+func (e *InvalidRawError) Error() string {
+	return fmt.Sprintf("invalid raw condition %q %+v: %s", e.Clause, e.Vars, e.Reason)
+}
+
+// Is reports whether the target is ErrInvalidRaw
+func (e *InvalidRawError) Is(target error) bool {
+	return target == ErrInvalidRaw
+}
+
+// NotEvaluableError represents the error when the condition can't be evaluated in memory by Plan.Match
+type NotEvaluableError struct {
+	// What can't be evaluated, e.g: "raw condition"
+	Condition string
+}
+
+func (e *NotEvaluableError) Error() string {
+	return fmt.Sprintf("can't evaluate %s in memory", e.Condition)
+}
+
+// Is reports whether the target is ErrNotEvaluable
+func (e *NotEvaluableError) Is(target error) bool {
+	return target == ErrNotEvaluable
+}
+
+// NotRenderableError represents the error when the condition can't be rendered for a target other than SQL
+type NotRenderableError struct {
+	// What can't be rendered, e.g: "raw condition"
+	Condition string
+	// The target, e.g: "MongoDB"
+	Target string
+}
+
+func (e *NotRenderableError) Error() string {
+	return fmt.Sprintf("can't render %s for %s", e.Condition, e.Target)
+}
+
+// Is reports whether the target is ErrNotRenderable
+func (e *NotRenderableError) Is(target error) bool {
+	return target == ErrNotRenderable
+}
+
+// ConditionError represents a problem of a single condition found while building the plan
+type ConditionError struct {
+	// The location of the condition in the input, e.g: "[1].budget__gte" is the "budget__gte" key of the 2nd given condition
+	Path string
+	// The field & operator parsed from the condition key, if any
+	Field    string
+	Operator string
+	// The given value of the condition
+	Value interface{}
+	// Machine-readable code of the problem, one of the Reason* constants
+	Reason string
+	// The underlying error, one of the typed errors above
+	Err error
+}
+
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ConditionError) Unwrap() error {
+	return e.Err
+}
+
+// Errors represents all problems found while building the plan
+type Errors []*ConditionError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the list of errors, which makes errors.Is and errors.As look into every single problem
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
@@ -1,6 +1,9 @@
 package gowhere
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestInvalidCond_Error(t *testing.T) {
 	type fields struct {
@@ -33,3 +36,60 @@ func TestInvalidCond_Error(t *testing.T) {
 		})
 	}
 }
+
+func TestErrors_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		errs Errors
+		want string
+	}{
+		{
+			name: "single error",
+			errs: Errors{
+				{Path: "[0].name__like", Err: &UnknownOperatorError{Field: "name", Operator: "like"}},
+			},
+			want: `[0].name__like: unknown operator "like" of field "name"`,
+		},
+		{
+			name: "multiple errors",
+			errs: Errors{
+				{Path: "[0].password", Err: &UnknownFieldError{Field: "password"}},
+				{Path: "[1]", Err: &UnsupportedTypeError{Value: 123}},
+			},
+			want: `[0].password: unknown field "password"; [1]: unsupported condition type int: 123`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.errs.Error(); got != tt.want {
+				t.Errorf("Errors.Error() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypedErrors_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "invalid value",
+			err:  &InvalidValueError{Field: "date", Operator: "between", Value: []string{"2019-04-13"}, Err: errors.New("expected a slice of two items")},
+			want: `invalid value [2019-04-13] of field "date" for operator "between": expected a slice of two items`,
+		},
+		{
+			name: "invalid raw",
+			err:  &InvalidRawError{Clause: "name = ?", Vars: []interface{}{"Go", 1}, Reason: "1 placeholder(s) but 2 var(s)"},
+			want: `invalid raw condition "name = ?" [Go 1]: 1 placeholder(s) but 2 var(s)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
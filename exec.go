@@ -0,0 +1,223 @@
+package gowhere
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrHavingQuery is returned by the execution helpers if the plan has conditions on the aggregate fields,
+// as the HAVING clause must come after the GROUP BY clause of the query. Use Plan.HavingSQL() instead
+var ErrHavingQuery = errors.New("gowhere: the HAVING conditions require a GROUP BY query")
+
+// ErrEmptyPlan is returned by Exec, and by Statement for the UPDATE & DELETE statements, if the plan has no WHERE clause,
+// e.g: all conditions are skipped in non-strict mode, so the statement would affect the whole table.
+// Set Config.AllowEmptyExec to run such statements
+var ErrEmptyPlan = errors.New("gowhere: the statement requires a WHERE clause")
+
+// Queryer is the interface to query the rows, implemented by *sql.DB, *sql.Tx and *sql.Conn
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Execer is the interface to execute a statement, implemented by *sql.DB, *sql.Tx and *sql.Conn
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Rebinder is the optional interface for a dialect which doesn't use "?" as the placeholder, e.g: $1 in PostgreSQL
+type Rebinder interface {
+	// Placeholder returns the placeholder of the nth var, starting from 1
+	Placeholder(n int) string
+}
+
+// literalMark replaces the "?" which are not placeholders in the built SQL, e.g: the jsonb operators of the raw
+// conditions without vars, so they're never bound
+const literalMark = "\x00"
+
+// markLiterals marks the "?" of the clause as literals, except the ones in the quoted strings & identifiers
+func markLiterals(clause string) string {
+	return replacePlaceholders(clause, func() string { return literalMark })
+}
+
+// unmarkLiterals restores the "?" marked by markLiterals
+func unmarkLiterals(sql string) string {
+	return strings.Replace(sql, literalMark, "?", -1)
+}
+
+// Statement returns the full statement of the query followed by the WHERE & ORDER BY clauses of the plan, with the args
+// for the database/sql package: the slices are expanded to one placeholder per item, e.g: IN (?, ?, ?), and the
+// placeholders are rebound for the dialect. The args are the vars of the placeholders in the query itself, if any.
+// Returns Plan.Error if the plan is invalid, without touching the query.
+// Returns ErrEmptyPlan for an UPDATE or DELETE statement without the WHERE clause, see Config.AllowEmptyExec.
+func (p *Plan) Statement(query string, args ...interface{}) (string, []interface{}, error) {
+	return p.statement(context.Background(), query, args, true, isWriteStatement(query))
+}
+
+// statement builds the full statement, optionally with the ORDER BY clause. The plan is built with the context if
+// it's not built yet. The WHERE clause is required for the writes, unless Config.AllowEmptyExec
+func (p *Plan) statement(ctx context.Context, query string, args []interface{}, order, write bool) (string, []interface{}, error) {
+	if !p.built {
+		p.BuildContext(ctx)
+	}
+	if p.Error != nil {
+		return "", nil, p.Error
+	}
+	if p.havingSQL != "" {
+		return "", nil, ErrHavingQuery
+	}
+	if write && p.stmtSQL == "" && !p.config.AllowEmptyExec {
+		return "", nil, ErrEmptyPlan
+	}
+
+	stmt := strings.TrimSpace(query)
+	vars := append(append([]interface{}{}, args...), p.vars...)
+	if p.stmtSQL != "" {
+		stmt += " WHERE " + p.stmtSQL
+	}
+	if order && p.orderSQL != "" {
+		stmt += " " + p.orderSQL
+	}
+
+	var placeholder func(n int) string
+	if rb, ok := p.config.Dialect.(Rebinder); ok {
+		placeholder = rb.Placeholder
+	}
+	stmt, vars = expandArgs(stmt, vars, placeholder)
+	return unmarkLiterals(stmt), vars, nil
+}
+
+// Query executes the query with the conditions of the plan, e.g: plan.Query(ctx, db, "SELECT * FROM trips").
+// Like QueryRow, Exec & Count, the plan is built with the context if it's not built yet, see BuildContext
+func (p *Plan) Query(ctx context.Context, db Queryer, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, vars, err := p.statement(ctx, query, args, true, false)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, stmt, vars...)
+}
+
+// QueryRow executes the query with the conditions of the plan, and scans the first row into dest.
+// Returns sql.ErrNoRows if there are no rows
+func (p *Plan) QueryRow(ctx context.Context, db Queryer, query string, dest ...interface{}) error {
+	stmt, vars, err := p.statement(ctx, query, nil, true, false)
+	if err != nil {
+		return err
+	}
+	return scanRow(ctx, db, stmt, vars, dest)
+}
+
+// scanRow queries the statement and scans the first row into dest
+func scanRow(ctx context.Context, db Queryer, stmt string, vars []interface{}, dest []interface{}) error {
+	rows, err := db.QueryContext(ctx, stmt, vars...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// Exec executes the statement with the conditions of the plan, e.g: plan.Exec(ctx, db, "UPDATE trips SET status = ?", "done").
+// Returns ErrEmptyPlan if the plan has no WHERE clause, see Config.AllowEmptyExec
+func (p *Plan) Exec(ctx context.Context, db Execer, query string, args ...interface{}) (sql.Result, error) {
+	stmt, vars, err := p.statement(ctx, query, args, true, true)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, stmt, vars...)
+}
+
+// Count returns the number of rows of the table matching the conditions of the plan. Default to the Table config
+func (p *Plan) Count(ctx context.Context, db Queryer, table string) (int64, error) {
+	if table == "" {
+		table = p.config.Table
+	}
+	if table == "" {
+		return 0, errors.New("gowhere: missing table to count")
+	}
+
+	// the sorting doesn't matter
+	stmt, vars, err := p.statement(ctx, "SELECT COUNT(*) FROM "+p.config.Dialect.QuoteIdentifier(table), nil, false, false)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = scanRow(ctx, db, stmt, vars, []interface{}{&count})
+	return count, err
+}
+
+// isWriteStatement reports whether the query is an UPDATE or DELETE statement
+func isWriteStatement(query string) bool {
+	fields := strings.Fields(query)
+	return len(fields) > 0 && (strings.EqualFold(fields[0], "UPDATE") || strings.EqualFold(fields[0], "DELETE"))
+}
+
+// expandArgs expands the slice args to one placeholder per item, as database/sql only accepts the scalar args.
+// An empty slice is replaced by NULL, so "IN (NULL)" matches nothing. The placeholders of the args are rebound by the
+// given func if not nil, the other "?" are kept unchanged
+func expandArgs(query string, args []interface{}, placeholder func(n int) string) (string, []interface{}) {
+	expanded := make([]interface{}, 0, len(args))
+	bind := func(arg interface{}) string {
+		expanded = append(expanded, arg)
+		if placeholder == nil {
+			return "?"
+		}
+		return placeholder(len(expanded))
+	}
+
+	i := 0
+	query = replacePlaceholders(query, func() string {
+		if i >= len(args) {
+			return "?"
+		}
+		arg := args[i]
+		i++
+
+		rv := reflect.ValueOf(arg)
+		if arg == nil || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+			return bind(arg)
+		}
+		if rv.Len() == 0 {
+			return "NULL"
+		}
+		items := make([]string, rv.Len())
+		for j := range items {
+			items[j] = bind(Utils.ToSQLVar(rv.Index(j).Interface()))
+		}
+		return strings.Join(items, ", ")
+	})
+	return query, append(expanded, args[i:]...)
+}
+
+// replacePlaceholders replaces each "?" placeholder of the query, except the ones in the quoted strings & identifiers
+func replacePlaceholders(query string, replace func() string) string {
+	var b strings.Builder
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			b.WriteString(replace())
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
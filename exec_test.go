@@ -0,0 +1,194 @@
+package gowhere
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// recordDriver records the executed statements, and returns a single row of the count
+type recordDriver struct {
+	query string
+	args  []interface{}
+}
+
+func (d *recordDriver) Open(name string) (driver.Conn, error) { return d, nil }
+func (d *recordDriver) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+func (d *recordDriver) Close() error              { return nil }
+func (d *recordDriver) Begin() (driver.Tx, error) { return nil, errors.New("not implemented") }
+
+func (d *recordDriver) record(query string, args []driver.NamedValue) {
+	d.query, d.args = query, make([]interface{}, len(args))
+	for i, arg := range args {
+		d.args[i] = arg.Value
+	}
+}
+
+func (d *recordDriver) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	d.record(query, args)
+	return &recordRows{}, nil
+}
+
+func (d *recordDriver) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	d.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+type recordRows struct{ done bool }
+
+func (r *recordRows) Columns() []string { return []string{"count"} }
+func (r *recordRows) Close() error      { return nil }
+func (r *recordRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(3)
+	return nil
+}
+
+func TestPlan_Statement(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     *Plan
+		query    string
+		args     []interface{}
+		wantStmt string
+		wantVars []interface{}
+	}{
+		{
+			name:     "postgresql",
+			plan:     Where(map[string]interface{}{"id__in": []int{1, 2}, "name": "it's ?"}).Where("note <> '?'").OrderBy("-id"),
+			query:    "SELECT * FROM trips",
+			wantStmt: `SELECT * FROM trips WHERE ("id" IN ($1, $2) AND "name" = $3) AND (note <> '?') ORDER BY "id" DESC`,
+			wantVars: []interface{}{1, 2, "it's ?"},
+		},
+		{
+			name:     "mysql with args",
+			plan:     WithConfig(Config{Dialect: DialectMySQL}).Where(map[string]interface{}{"id__in": []string{}, "data__exact": []byte("x")}),
+			query:    "UPDATE trips SET status = ?",
+			args:     []interface{}{"done"},
+			wantStmt: "UPDATE trips SET status = ? WHERE (`data` = ? AND `id` IN (NULL))",
+			wantVars: []interface{}{"done", "x"},
+		},
+		{
+			name:     "empty lists",
+			plan:     Where(map[string]interface{}{"id__in": []int{}, "status__notin": []string{}, "name": "Go"}),
+			query:    "SELECT * FROM trips",
+			wantStmt: `SELECT * FROM trips WHERE ("id" IN (NULL) AND "name" = $1 AND 1 = 1)`,
+			wantVars: []interface{}{"Go"},
+		},
+		{
+			name:     "literal question marks",
+			plan:     Where("data ? 'k'").Where(map[string]interface{}{"id": 1}),
+			query:    "SELECT * FROM trips",
+			wantStmt: `SELECT * FROM trips WHERE (data ? 'k') AND ("id" = $1)`,
+			wantVars: []interface{}{1},
+		},
+		{
+			name:     "empty plan",
+			plan:     Where(map[string]interface{}{}),
+			query:    " SELECT * FROM trips ",
+			wantStmt: "SELECT * FROM trips",
+			wantVars: []interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, vars, err := tt.plan.Statement(tt.query, tt.args...)
+			if err != nil {
+				t.Fatalf("Plan.Statement() error = %v", err)
+			}
+			if stmt != tt.wantStmt {
+				t.Errorf("Plan.Statement() stmt = %v, want %v", stmt, tt.wantStmt)
+			}
+			if !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("Plan.Statement() vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+
+	// the literal question marks are only kept out of the statements
+	if sql := Where("data ? 'k'").SQL(); sql != "(data ? 'k')" {
+		t.Errorf("Plan.SQL() = %q, want (data ? 'k')", sql)
+	}
+}
+
+func TestPlan_Exec(t *testing.T) {
+	rec := &recordDriver{}
+	sql.Register("gowhere_record", rec)
+	db, err := sql.Open("gowhere_record", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	plan := WithConfig(Config{Table: "trips"}).Where(map[string]interface{}{"id__gt": 1}).OrderBy("id")
+	count, err := plan.Count(ctx, db, "")
+	if err != nil || count != 3 {
+		t.Fatalf("Plan.Count() = %v, %v, want 3", count, err)
+	}
+	if want := `SELECT COUNT(*) FROM "trips" WHERE ("trips"."id" > $1)`; rec.query != want {
+		t.Errorf("Plan.Count() query = %v, want %v", rec.query, want)
+	}
+
+	var n int64
+	if err := plan.QueryRow(ctx, db, "SELECT id FROM trips", &n); err != nil || n != 3 {
+		t.Errorf("Plan.QueryRow() = %v, %v, want 3", n, err)
+	}
+	if want := `SELECT id FROM trips WHERE ("trips"."id" > $1) ORDER BY "trips"."id" ASC`; rec.query != want {
+		t.Errorf("Plan.QueryRow() query = %v, want %v", rec.query, want)
+	}
+
+	if _, err := plan.Exec(ctx, db, "DELETE FROM trips"); err != nil {
+		t.Errorf("Plan.Exec() error = %v", err)
+	}
+	if !reflect.DeepEqual(rec.args, []interface{}{int64(1)}) {
+		t.Errorf("Plan.Exec() args = %v, want [1]", rec.args)
+	}
+
+	// the plan is built with the context of the query
+	scoped := WithConfig(Config{Table: "trips"}).Scope(map[string]interface{}{"tenant_id": FromContext(ctxKey("tenant_id"))})
+	if _, err := scoped.Query(context.WithValue(ctx, ctxKey("tenant_id"), 7), db, "SELECT * FROM trips"); err != nil {
+		t.Errorf("Plan.Query() error = %v", err)
+	}
+	if want := `SELECT * FROM trips WHERE ("trips"."tenant_id" = $1)`; rec.query != want || !reflect.DeepEqual(rec.args, []interface{}{int64(7)}) {
+		t.Errorf("Plan.Query() query = %v %v, want %v [7]", rec.query, rec.args, want)
+	}
+
+	// invalid plans never touch the database
+	rec.query = ""
+	invalid := WithConfig(Config{Strict: true}).Where(map[string]interface{}{"id__unknown": 1})
+	if _, err := invalid.Query(ctx, db, "SELECT * FROM trips"); !errors.Is(err, ErrUnknownOperator) {
+		t.Errorf("Plan.Query() error = %v, want ErrUnknownOperator", err)
+	}
+	having := WithConfig(Config{AggregateFields: map[string]string{"total": "COUNT(*)"}}).Where(map[string]interface{}{"total__gt": 1})
+	if _, err := having.Exec(ctx, db, "DELETE FROM trips"); err != ErrHavingQuery {
+		t.Errorf("Plan.Exec() error = %v, want ErrHavingQuery", err)
+	}
+	// the skipped conditions never turn a write into a full table one
+	empty := Where(map[string]interface{}{"id__foo": 5})
+	if _, err := empty.Exec(ctx, db, "UPDATE trips SET status = ?", "done"); err != ErrEmptyPlan {
+		t.Errorf("Plan.Exec() error = %v, want ErrEmptyPlan", err)
+	}
+	if _, _, err := empty.Statement(" delete FROM trips"); err != ErrEmptyPlan {
+		t.Errorf("Plan.Statement() error = %v, want ErrEmptyPlan", err)
+	}
+	if rec.query != "" {
+		t.Errorf("unexpected query %v", rec.query)
+	}
+	if stmt, _, err := empty.Statement("SELECT * FROM trips"); err != nil || stmt != "SELECT * FROM trips" {
+		t.Errorf("Plan.Statement() = %v, %v, want SELECT * FROM trips", stmt, err)
+	}
+	all := WithConfig(Config{AllowEmptyExec: true}).Where(map[string]interface{}{"id__foo": 5})
+	if _, err := all.Exec(ctx, db, "DELETE FROM trips"); err != nil || rec.query != "DELETE FROM trips" {
+		t.Errorf("Plan.Exec() = %v, %v, want DELETE FROM trips", rec.query, err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package gormwhere adapts the gowhere plans to GORM scopes:
+//
+//	plan := gowhere.Where(filters)
+//	db.Model(&Trip{}).Scopes(gormwhere.Scope(plan)).Find(&trips)
+//
+// It's a separate module, so the gowhere package stays free of dependencies.
+package gormwhere
+
+import (
+	"strings"
+
+	"github.com/imdatngo/gowhere"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Scope returns the GORM scope which adds the conditions, the HAVING conditions and the sorting of the plan.
+// The Table config is taken from the statement, i.e: db.Table() or the table of the model, and the column aliases
+// are derived from the schema of the model, see ColumnAliases. The build errors of the plan are added to the db.
+// Note: the plan is updated by the scope, so don't share it between goroutines. The Dialect config of the plan should
+// match the database, e.g: gowhere.DialectMySQL for MySQL.
+func Scope(plan *gowhere.Plan) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		stmt := db.Statement
+		model := stmt.Model
+		if model == nil {
+			model = stmt.Dest
+		}
+		if stmt.Schema == nil && model != nil {
+			// the errors are reported by GORM when the statement is executed
+			_ = stmt.Parse(model)
+		}
+
+		if stmt.Table != "" {
+			plan.SetTable(stmt.Table)
+		}
+		if stmt.Schema != nil {
+			plan.SetColumnAliases(ColumnAliases(stmt.Schema))
+		}
+
+		plan.BuildContext(stmt.Context)
+		if plan.Error != nil {
+			_ = db.AddError(plan.Error)
+			return db
+		}
+
+		if sql := plan.SQL(); sql != "" {
+			db = db.Where(sql, plan.Vars()...)
+		}
+		if having := plan.HavingSQL(); having != "" {
+			db = db.Having(having, plan.HavingVars()...)
+		}
+		if order := plan.OrderSQL(); order != "" {
+			db = db.Order(strings.TrimPrefix(order, "ORDER BY "))
+		}
+		return db
+	}
+}
+
+// ColumnAliases returns the column aliases of the schema: the names & the JSON names of the fields to their columns,
+// e.g: {"CreatedAt": "created_at", "createdAt": "created_at"}, so the conditions can use the names of the API
+func ColumnAliases(s *schema.Schema) map[string]string {
+	aliases := make(map[string]string)
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		names := []string{field.Name}
+		if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			names = append(names, name)
+		}
+		for _, name := range names {
+			if name != field.DBName {
+				aliases[name] = field.DBName
+			}
+		}
+	}
+	return aliases
+}
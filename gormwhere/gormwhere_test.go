@@ -0,0 +1,96 @@
+package gormwhere
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/imdatngo/gowhere"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// dryRunDialector renders the statements of PostgreSQL without a database
+type dryRunDialector struct{}
+
+func (dryRunDialector) Name() string { return "postgres" }
+func (dryRunDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	return nil
+}
+func (d dryRunDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return migrator.Migrator{Config: migrator.Config{DB: db, Dialector: d}}
+}
+func (dryRunDialector) DataTypeOf(*schema.Field) string                { return "" }
+func (dryRunDialector) DefaultValueOf(*schema.Field) clause.Expression { return clause.Expr{} }
+func (dryRunDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte('?')
+}
+func (dryRunDialector) QuoteTo(writer clause.Writer, str string) {
+	writer.WriteString(`"` + strings.Replace(str, ".", `"."`, -1) + `"`)
+}
+func (dryRunDialector) Explain(sql string, vars ...interface{}) string { return sql }
+
+type Trip struct {
+	ID        int
+	Name      string
+	CreatedBy string `json:"createdBy"`
+	Budget    int    `json:"-"`
+}
+
+func newDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(dryRunDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestScope(t *testing.T) {
+	db := newDB(t)
+	plan := gowhere.Where(map[string]interface{}{"createdBy": "gopher", "id__in": []int{1, 2}}).OrderBy("-ID")
+
+	var trips []Trip
+	stmt := db.Scopes(Scope(plan)).Find(&trips).Statement
+	want := `SELECT * FROM "trips" WHERE ("trips"."created_by" = ? AND "trips"."id" IN (?,?)) ORDER BY "trips"."id" DESC`
+	if got := stmt.SQL.String(); got != want {
+		t.Errorf("SQL = %v, want %v", got, want)
+	}
+	if want := []interface{}{"gopher", 1, 2}; !reflect.DeepEqual(stmt.Vars, want) {
+		t.Errorf("Vars = %v, want %v", stmt.Vars, want)
+	}
+
+	// the table of the statement
+	stmt = db.Table("archived_trips").Scopes(Scope(gowhere.Where(map[string]interface{}{"name": "x"}))).Find(&trips).Statement
+	want = `SELECT * FROM "archived_trips" WHERE ("archived_trips"."name" = ?)`
+	if got := stmt.SQL.String(); got != want {
+		t.Errorf("SQL = %v, want %v", got, want)
+	}
+}
+
+func TestScope_Error(t *testing.T) {
+	db := newDB(t)
+	plan := gowhere.WithConfig(gowhere.Config{Strict: true}).Where(map[string]interface{}{"name__unknown": "x"})
+
+	var trips []Trip
+	if err := db.Scopes(Scope(plan)).Find(&trips).Error; !errors.Is(err, gowhere.ErrUnknownOperator) {
+		t.Errorf("Error = %v, want ErrUnknownOperator", err)
+	}
+}
+
+func TestColumnAliases(t *testing.T) {
+	db := newDB(t)
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&Trip{}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"ID": "id", "Name": "name", "CreatedBy": "created_by", "createdBy": "created_by", "Budget": "budget"}
+	if got := ColumnAliases(stmt.Schema); !reflect.DeepEqual(got, want) {
+		t.Errorf("ColumnAliases() = %v, want %v", got, want)
+	}
+}
@@ -21,7 +21,7 @@ func WithConfig(conf Config) *Plan {
 		}
 	}
 
-	return &Plan{config: &conf, conditions: &andConditions{naked: true}}
+	return &Plan{config: &conf, conditions: &andConditions{naked: true}, scopes: &andConditions{naked: true}}
 }
 
 // Where is shortcut to create new plan with default configurations
@@ -1,8 +1,10 @@
 package gowhere
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestWhere(t *testing.T) {
@@ -113,7 +115,6 @@ func TestWhere(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.cfg.sort = true
 			plan := WithConfig(tt.cfg).Where(tt.args.cond, tt.args.vars...)
 			sql := plan.SQL()
 			vars := plan.Vars()
@@ -132,3 +133,522 @@ func TestWhere(t *testing.T) {
 		})
 	}
 }
+
+func TestPlan_Fingerprint(t *testing.T) {
+	cond := map[string]interface{}{
+		"budget__gte":    1000,
+		"name__contains": "Gopher",
+		"id":             []int{1, 2, 3},
+		"started_at":     nil,
+	}
+	want := Where(cond).SQL()
+	fingerprint := Where(cond).Fingerprint()
+
+	// map iteration order is random, make sure the output is stable
+	for i := 0; i < 20; i++ {
+		if sql := Where(cond).SQL(); sql != want {
+			t.Fatalf("sql = %v, want %v", sql, want)
+		}
+	}
+
+	tests := []struct {
+		name string
+		plan *Plan
+		same bool
+	}{
+		{
+			name: "different vars",
+			plan: Where(map[string]interface{}{
+				"budget__gte":    2000,
+				"name__contains": "Go",
+				"id":             []int{4},
+				"started_at":     nil,
+			}),
+			same: true,
+		},
+		{
+			name: "different operator",
+			plan: Where(map[string]interface{}{
+				"budget__lte":    1000,
+				"name__contains": "Gopher",
+				"id":             []int{1, 2, 3},
+				"started_at":     nil,
+			}),
+			same: false,
+		},
+		{
+			name: "raw condition with extra spaces",
+			plan: Where("name = ?   AND\n budget >= ?", "Go", 2000),
+			same: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.plan.Fingerprint(); (got == fingerprint) != tt.same {
+				t.Errorf("Plan.Fingerprint() = %v, compared to %v, want same = %v", got, fingerprint, tt.same)
+			}
+		})
+	}
+
+	if Where("name = ?   AND\n budget >= ?", "Go", 2000).Fingerprint() != Where("name = ? AND budget >= ?", "Gopher", 1).Fingerprint() {
+		t.Errorf("Plan.Fingerprint() should ignore whitespaces and vars of raw conditions")
+	}
+	if Where("name = 'a  b'").Fingerprint() == Where("name = 'a b'").Fingerprint() {
+		t.Errorf("Plan.Fingerprint() should keep whitespaces of quoted literals")
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	plan := WithConfig(Config{Strict: true}).
+		Where(map[string]interface{}{"name": "Gopher"}).
+		Where(map[string]interface{}{
+			"budget__gte":   1000,
+			"budget__above": 2000,
+			"name__like":    "Go",
+		}).
+		Where(123).
+		Where([]interface{}{
+			map[string]interface{}{"name": "Go"},
+			map[string]interface{}{"members__most": 3},
+		})
+
+	if _, ok := plan.Error.(Errors); !ok {
+		t.Fatalf("unsupported condition should fail the plan right away, got: %+v", plan.Error)
+	}
+
+	errs, ok := plan.Build().Error.(Errors)
+	if !ok {
+		t.Fatalf("Error = %+v, want Errors", plan.Error)
+	}
+
+	want := []struct {
+		path     string
+		field    string
+		operator string
+		value    interface{}
+		reason   string
+	}{
+		{"[1].budget__above", "budget", "above", 2000, ReasonUnknownOperator},
+		{"[1].name__like", "name", "like", "Go", ReasonUnknownOperator},
+		{"[2]", "", "", 123, ReasonUnsupportedType},
+		{"[3][1].members__most", "members", "most", 3, ReasonUnknownOperator},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, w := range want {
+		e := errs[i]
+		if e.Path != w.path || e.Field != w.field || e.Operator != w.operator || e.Value != w.value || e.Reason != w.reason {
+			t.Errorf("error #%d = %+v, want %+v", i, e, w)
+		}
+	}
+
+	var ce *ConditionError
+	if !errors.As(errors.Join(errors.New("other"), plan.Error), &ce) || ce.Path != "[1].budget__above" {
+		t.Errorf("errors.As() should find the first ConditionError, got: %+v", ce)
+	}
+	var oe *UnknownOperatorError
+	if !errors.As(plan.Error, &oe) || oe.Field != "budget" || oe.Operator != "above" {
+		t.Errorf("errors.As() should find the first UnknownOperatorError, got: %+v", oe)
+	}
+	if !errors.Is(plan.Error, ErrUnsupportedType) || errors.Is(plan.Error, ErrInvalidValue) {
+		t.Errorf("errors.Is() doesn't match the sentinel errors: %v", plan.Error)
+	}
+
+	// non-strict mode skips the invalid conditions and reports them as warnings
+	plan = Where(map[string]interface{}{"name": "Gopher", "budget__above": 2000}).Where(123)
+	if plan.SQL() != `("name" = ?)` || plan.Error != nil {
+		t.Errorf("sql = %v, error = %v", plan.SQL(), plan.Error)
+	}
+	if w := plan.Warnings(); len(w) != 2 || w[0].Path != "[0].budget__above" || w[1].Path != "[1]" {
+		t.Errorf("Warnings() = %v", w)
+	}
+}
+
+func TestBuildErrors_Reasons(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		cond     interface{}
+		vars     []interface{}
+		wantErr  error
+		wantPath string
+		wantSQL  string
+	}{
+		{
+			name:     "unknown field",
+			cfg:      Config{AllowedFields: map[string][]string{"name": nil}},
+			cond:     map[string]interface{}{"name": "Go", "password": "secret"},
+			wantErr:  ErrUnknownField,
+			wantPath: "[0].password",
+			wantSQL:  `("name" = ?)`,
+		},
+		{
+			name:     "forbidden operator",
+			cfg:      Config{AllowedFields: map[string][]string{"name": {"exact", "icontains"}}},
+			cond:     map[string]interface{}{"name__icontains": "Go", "name__startswith": "G"},
+			wantErr:  ErrUnknownOperator,
+			wantPath: "[0].name__startswith",
+			wantSQL:  `(LOWER("name") LIKE LOWER(?))`,
+		},
+		{
+			name:     "forbidden implied operator",
+			cfg:      Config{AllowedFields: map[string][]string{"name": {"icontains"}}},
+			cond:     map[string]interface{}{"name": []string{"a"}, "name__icontains": "Go"},
+			wantErr:  ErrUnknownOperator,
+			wantPath: "[0].name",
+			wantSQL:  `(LOWER("name") LIKE LOWER(?))`,
+		},
+		{
+			name: "custom condition is trusted",
+			cfg: Config{
+				AllowedFields: map[string][]string{"name": nil},
+				CustomConditions: map[string]CustomConditionFn{
+					"search": func(key string, val interface{}, cfg *Config) interface{} {
+						return map[string]interface{}{"title__contains": val}
+					},
+				},
+			},
+			cond:    map[string]interface{}{"search": "Go"},
+			wantSQL: `(("title" LIKE ?))`,
+		},
+		{
+			name:     "between without two items",
+			cond:     map[string]interface{}{"date__between": []string{"2019-04-13"}},
+			wantErr:  ErrInvalidValue,
+			wantPath: "[0].date__between",
+		},
+		{
+			name:     "between with more than two items",
+			cond:     map[string]interface{}{"budget__between": []interface{}{1, 2, 3}},
+			wantErr:  ErrInvalidValue,
+			wantPath: "[0].budget__between",
+		},
+		{
+			name:     "unsupported custom condition result",
+			cond:     map[string]interface{}{"search": "Go"},
+			wantErr:  ErrUnsupportedType,
+			wantPath: "[0].search",
+			cfg: Config{
+				CustomConditions: map[string]CustomConditionFn{
+					"search": func(key string, val interface{}, cfg *Config) interface{} {
+						return 123
+					},
+				},
+			},
+		},
+		{
+			name:    "raw condition without vars",
+			cond:    `data ? 'name'`,
+			wantSQL: `(data ? 'name')`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Strict = true
+			plan := WithConfig(tt.cfg).Where(tt.cond, tt.vars...).Build()
+
+			if !errors.Is(plan.Error, tt.wantErr) || (tt.wantErr == nil && plan.Error != nil) {
+				t.Fatalf("Error = %v, want %v", plan.Error, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if errs := plan.Error.(Errors); len(errs) != 1 || errs[0].Path != tt.wantPath {
+					t.Errorf("Error = %v, want path %v", plan.Error, tt.wantPath)
+				}
+			}
+
+			// non-strict mode skips the invalid condition with a warning
+			plan = WithConfig(tt.cfg).Where(tt.cond, tt.vars...)
+			plan.config.Strict = false
+			if sql := plan.SQL(); sql != tt.wantSQL || plan.Error != nil {
+				t.Errorf("sql = %v, want %v, error = %v", sql, tt.wantSQL, plan.Error)
+			}
+			warnings := plan.Warnings()
+			if tt.wantErr == nil && warnings != nil {
+				t.Errorf("Warnings() = %v, want nil", warnings)
+			}
+			if tt.wantErr != nil && (len(warnings) != 1 || warnings[0].Path != tt.wantPath || !errors.Is(warnings, tt.wantErr)) {
+				t.Errorf("Warnings() = %v, want %v at %v", warnings, tt.wantErr, tt.wantPath)
+			}
+		})
+	}
+
+	// the operators implied by the values are checked by their names
+	allowed := Config{Strict: true, AllowedFields: map[string][]string{"name": {"icontains"}, "tag": {"isnull", "exact"}}}
+	for _, val := range []interface{}{nil, "x"} {
+		if plan := WithConfig(allowed).Where(map[string]interface{}{"name": val}).Build(); !errors.Is(plan.Error, ErrUnknownOperator) {
+			t.Errorf("Error = %v, want %v", plan.Error, ErrUnknownOperator)
+		}
+		if plan := WithConfig(allowed).Where(map[string]interface{}{"tag": val}).Build(); plan.Error != nil {
+			t.Errorf("unexpected error: %v", plan.Error)
+		}
+	}
+
+	// the quoted question marks aren't placeholders
+	plan := Where("name = '?' AND id = ?", 5).Build()
+	if sql := plan.SQL(); plan.Error != nil || sql != "(name = '?' AND id = ?)" {
+		t.Errorf("sql = %v, error = %v, want (name = '?' AND id = ?)", sql, plan.Error)
+	}
+
+	// malformed raw conditions fail the plan closed, even if not strict
+	for _, plan := range []*Plan{
+		Where("name = ? AND budget >= ?", "Go").Build(),
+		Where("id = $1", 5).Where(map[string]interface{}{"name": "Go"}).Build(),
+	} {
+		var errs Errors
+		if !errors.As(plan.Error, &errs) || len(errs) != 1 || errs[0].Path != "[0]" || !errors.Is(errs, ErrInvalidRaw) {
+			t.Errorf("Error = %v, want %v at [0]", plan.Error, ErrInvalidRaw)
+		}
+		if sql := plan.SQL(); sql != "(1 = 0)" {
+			t.Errorf("sql = %v, want (1 = 0)", sql)
+		}
+	}
+}
+
+func TestPlan_Scope(t *testing.T) {
+	plan := WithConfig(Config{AllowedFields: map[string][]string{"name": nil, "budget": nil}}).
+		Where(map[string]interface{}{"name": "Gopher"}).
+		Scope(map[string]interface{}{"tenant_id": 7}).
+		Or(map[string]interface{}{"budget__gte": 1000}).
+		Not("deleted = ?", true).
+		Scope("visible = ?", true)
+
+	wantSQL := `("tenant_id" = ?) AND (visible = ?) AND ((("name" = ?)) OR ("budget" >= ?)) AND NOT (deleted = ?)`
+	wantVars := []interface{}{7, true, "Gopher", 1000, true}
+	if sql := plan.SQL(); sql != wantSQL {
+		t.Errorf("sql = %v, want %v", sql, wantSQL)
+	}
+	if vars := plan.Vars(); !reflect.DeepEqual(vars, wantVars) {
+		t.Errorf("vars = %v, want %v", vars, wantVars)
+	}
+	if plan.Error != nil || plan.Warnings() != nil {
+		t.Errorf("unexpected error: %v, warnings: %v", plan.Error, plan.Warnings())
+	}
+
+	// only scopes
+	plan = WithConfig(Config{}).Scope(map[string]interface{}{"tenant_id": 7})
+	if sql := plan.SQL(); sql != `("tenant_id" = ?)` {
+		t.Errorf("sql = %v, want %v", sql, `("tenant_id" = ?)`)
+	}
+
+	// invalid scope always fails the plan, even in non-strict mode
+	plan = Where(map[string]interface{}{"name": "Gopher"}).Scope(map[string]interface{}{"tenant_id__equals": 7})
+	if sql := plan.SQL(); sql != "(1 = 0)" || !errors.Is(plan.Error, ErrUnknownOperator) {
+		t.Errorf("sql = %v, error = %v", sql, plan.Error)
+	}
+	if errs := plan.Error.(Errors); errs[0].Path != "scope[0].tenant_id__equals" {
+		t.Errorf("Error = %v", plan.Error)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, time.March, 13, 15, 30, 45, 0, time.UTC)
+	cfg := Config{
+		Now:        func() time.Time { return now },
+		TimeFields: []string{"paid_at"},
+	}
+
+	tests := []struct {
+		name     string
+		cond     map[string]interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "date",
+			cond:     map[string]interface{}{"created_at__date": "yesterday"},
+			wantSQL:  `(DATE("created_at") = ?)`,
+			wantVars: []interface{}{"2024-03-12"},
+		},
+		{
+			name:     "between range",
+			cond:     map[string]interface{}{"paid_at__between": "-1w..now"},
+			wantSQL:  `("paid_at" BETWEEN ? AND ?)`,
+			wantVars: []interface{}{"2024-03-06 15:30:45", "2024-03-13 15:30:45"},
+		},
+		{
+			name:     "between of other fields",
+			cond:     map[string]interface{}{"name__between": []string{"now", "today"}},
+			wantSQL:  `("name" BETWEEN ? AND ?)`,
+			wantVars: []interface{}{"now", "today"},
+		},
+		{
+			name:     "datebetween items",
+			cond:     map[string]interface{}{"created_at__datebetween": []string{"startOf:month", "today"}},
+			wantSQL:  `(DATE("created_at") BETWEEN ? AND ?)`,
+			wantVars: []interface{}{"2024-03-01", "2024-03-13"},
+		},
+		{
+			name:     "time fields",
+			cond:     map[string]interface{}{"paid_at__gte": "now-1h", "name__gte": "now-1h"},
+			wantSQL:  `("name" >= ? AND "paid_at" >= ?)`,
+			wantVars: []interface{}{"now-1h", "2024-03-13 14:30:45"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := WithConfig(cfg).Where(tt.cond)
+			if sql := plan.SQL(); sql != tt.wantSQL {
+				t.Errorf("sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if vars := plan.Vars(); !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestTimeZone(t *testing.T) {
+	hcm := time.FixedZone("Asia/Ho_Chi_Minh", 7*3600)
+	// 2024-03-01 in Ho Chi Minh, but still 2024-02-29 in UTC
+	now := time.Date(2024, time.February, 29, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		cond     map[string]interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "without location",
+			cond:     map[string]interface{}{"created_at__date": "2024-03-01T01:00:00+07:00", "paid_at__date": "today"},
+			wantSQL:  `(DATE("created_at") = ? AND DATE("paid_at") = ?)`,
+			wantVars: []interface{}{"2024-03-01", "2024-02-29"},
+		},
+		{
+			name: "convert values",
+			cfg:  Config{Location: hcm, TimeFields: []string{"started_at"}},
+			cond: map[string]interface{}{
+				"created_at__date":    "2024-02-29T18:00:00Z",
+				"paid_at__date":       "today",
+				"started_at__between": []time.Time{time.Date(2024, 2, 29, 17, 0, 0, 0, time.UTC), now},
+			},
+			wantSQL:  `(DATE("created_at") = ? AND DATE("paid_at") = ? AND "started_at" BETWEEN ? AND ?)`,
+			wantVars: []interface{}{"2024-03-01", "2024-03-01", "2024-03-01 00:00:00+07:00", "2024-03-01 03:00:00+07:00"},
+		},
+		{
+			name: "keep offsets",
+			cfg:  Config{Location: hcm, TimeFields: []string{"paid_at"}},
+			cond: map[string]interface{}{
+				"paid_at__gte":        "2024-03-01T10:00:00+07:00",
+				"started_at__between": []string{"2024-03-01T10:00:00.5+07:00", "2024-03-02T10:00:00Z"},
+			},
+			wantSQL:  `("paid_at" >= ? AND "started_at" BETWEEN ? AND ?)`,
+			wantVars: []interface{}{"2024-03-01 10:00:00+07:00", "2024-03-01T10:00:00.5+07:00", "2024-03-02T10:00:00Z"},
+		},
+		{
+			name:     "convert columns with naive values",
+			cfg:      Config{Location: hcm, ConvertTimeZone: true, TimeFields: []string{"paid_at"}},
+			cond:     map[string]interface{}{"paid_at__between": []string{"2024-03-01T10:00:00Z", "now"}},
+			wantSQL:  `(("paid_at" AT TIME ZONE ?) BETWEEN ? AND ?)`,
+			wantVars: []interface{}{"Asia/Ho_Chi_Minh", "2024-03-01 17:00:00", "2024-03-01 03:00:00"},
+		},
+		{
+			name: "convert columns",
+			cfg:  Config{Location: hcm, ConvertTimeZone: true},
+			cond: map[string]interface{}{
+				"created_at__date":        "today",
+				"started_at__datebetween": []string{"yesterday", "today"},
+			},
+			wantSQL:  `(DATE(("created_at" AT TIME ZONE ?)) = ? AND DATE(("started_at" AT TIME ZONE ?)) BETWEEN ? AND ?)`,
+			wantVars: []interface{}{"Asia/Ho_Chi_Minh", "2024-03-01", "Asia/Ho_Chi_Minh", "2024-02-29", "2024-03-01"},
+		},
+		{
+			name:     "convert columns in mysql",
+			cfg:      Config{Location: hcm, ConvertTimeZone: true, Dialect: DialectMySQL, TimeFields: []string{"paid_at"}},
+			cond:     map[string]interface{}{"paid_at__gte": "today", "name": "Go"},
+			wantSQL:  "(`name` = ? AND CONVERT_TZ(`paid_at`, '+00:00', ?) >= ?)",
+			wantVars: []interface{}{"Go", "Asia/Ho_Chi_Minh", "2024-03-01"},
+		},
+		{
+			name:     "convert columns to offset",
+			cfg:      Config{Location: time.FixedZone("", 7*3600), ConvertTimeZone: true},
+			cond:     map[string]interface{}{"created_at__date": "today"},
+			wantSQL:  `(DATE(("created_at" AT TIME ZONE ?)) = ?)`,
+			wantVars: []interface{}{"UTC-07:00", "2024-03-01"},
+		},
+		{
+			name:     "convert columns to offset in mysql",
+			cfg:      Config{Location: time.FixedZone("", -3*3600-1800), ConvertTimeZone: true, Dialect: DialectMySQL},
+			cond:     map[string]interface{}{"created_at__date": "today"},
+			wantSQL:  "(DATE(CONVERT_TZ(`created_at`, '+00:00', ?)) = ?)",
+			wantVars: []interface{}{"-03:30", "2024-02-29"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Now = func() time.Time { return now }
+			plan := WithConfig(tt.cfg).Where(tt.cond)
+			if sql := plan.SQL(); sql != tt.wantSQL {
+				t.Errorf("sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if vars := plan.Vars(); !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+
+	// per-request override
+	plan := WithConfig(Config{Location: time.UTC, Now: func() time.Time { return now }}).Where(map[string]interface{}{"created_at__date": "today"})
+	if vars := plan.Vars(); !reflect.DeepEqual(vars, []interface{}{"2024-02-29"}) {
+		t.Errorf("vars = %v", vars)
+	}
+	if vars := plan.SetLocation(hcm).Vars(); !reflect.DeepEqual(vars, []interface{}{"2024-03-01"}) {
+		t.Errorf("vars = %v", vars)
+	}
+}
+
+func TestSargableDates(t *testing.T) {
+	hcm := time.FixedZone("Asia/Ho_Chi_Minh", 7*3600)
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		cond     map[string]interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name: "default form",
+			cond: map[string]interface{}{
+				"created_at__date": "2024-03-01",
+				"paid_at__year":    2024,
+			},
+			wantSQL:  `(DATE("created_at") = ? AND EXTRACT(YEAR FROM "paid_at") = ?)`,
+			wantVars: []interface{}{"2024-03-01", 2024},
+		},
+		{
+			name: "half-open ranges",
+			cfg:  Config{SargableDates: true},
+			cond: map[string]interface{}{
+				"a__date":        "2024-02-29",
+				"b__datebetween": []interface{}{time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), "2024-03-31"},
+				"c__year":        "2024",
+				"d__date":        Col("e"),
+			},
+			wantSQL:  `(("a" >= ? AND "a" < ?) AND ("b" >= ? AND "b" < ?) AND ("c" >= ? AND "c" < ?) AND DATE("d") = "e")`,
+			wantVars: []interface{}{"2024-02-29", "2024-03-01", "2024-03-01", "2024-04-01", "2024-01-01", "2025-01-01"},
+		},
+		{
+			name: "half-open ranges with location",
+			cfg:  Config{SargableDates: true, Location: hcm, ConvertTimeZone: true},
+			cond: map[string]interface{}{
+				"created_at__date": "2024-03-01",
+			},
+			wantSQL:  `(("created_at" >= ? AND "created_at" < ?))`,
+			wantVars: []interface{}{"2024-03-01 00:00:00+07:00", "2024-03-02 00:00:00+07:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := WithConfig(tt.cfg).Where(tt.cond)
+			if sql := plan.SQL(); sql != tt.wantSQL {
+				t.Errorf("sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if vars := plan.Vars(); !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package gowhere
+
+import "strings"
+
+// The clauses to build the conditions into
+const (
+	// all conditions, when there are no aggregate fields
+	clauseAll = iota
+	// the conditions on non-aggregate fields
+	clauseWhere
+	// the conditions on aggregate fields
+	clauseHaving
+)
+
+// HavingSQL returns the built HAVING clause, i.e: the conditions on the aggregate fields, ready to be added after
+// the GROUP BY clause. Empty if there are no such conditions
+func (p *Plan) HavingSQL() string {
+	if !p.built {
+		p.BuildContext(p.buildContext())
+	}
+	return p.havingSQL
+}
+
+// HavingVars returns the list of vars for the built HAVING clause
+func (p *Plan) HavingVars() []interface{} {
+	if !p.built {
+		p.BuildContext(p.buildContext())
+	}
+	return p.havingVars
+}
+
+// isAggregateField reports whether the field is an aggregate field
+func (c *Config) isAggregateField(field string) bool {
+	_, ok := c.AggregateFields[field]
+	return ok
+}
+
+// isAggregateCondition reports whether the condition is built into the HAVING clause, i.e: the field is an aggregate
+// field, or the value refers to an aggregate field, e.g: {"budget__lt": Col("total_spent")}
+func (c *Config) isAggregateCondition(field string, value interface{}) bool {
+	if c.isAggregateField(field) {
+		return true
+	}
+	switch v := value.(type) {
+	case ColumnValue:
+		return c.isAggregateField(v.Name)
+	case map[string]interface{}:
+		name, ok := v["$col"].(string)
+		return ok && len(v) == 1 && c.isAggregateField(name)
+	case []interface{}:
+		for _, item := range v {
+			if c.isAggregateCondition("", item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mixed reports whether the OR/NOT group has both aggregate and non-aggregate fields, which can't be split into the
+// WHERE and HAVING clauses. Such group is skipped by both clauses, and reported once
+func (s *buildState) mixed(value interface{}) bool {
+	if s.clause == clauseAll {
+		return false
+	}
+	where, having := clausesOf(value, s.cfg)
+	if !where || !having {
+		return false
+	}
+	if s.clause == clauseWhere {
+		s.report(&ConditionError{Value: value, Reason: ReasonMixedClauses, Err: ErrMixedClauses})
+	}
+	return true
+}
+
+// clausesOf returns whether the condition has conditions of the WHERE clause and of the HAVING clause
+func clausesOf(value interface{}, cfg *Config) (where, having bool) {
+	merge := func(items ...interface{}) {
+		for _, item := range items {
+			w, h := clausesOf(item, cfg)
+			where, having = where || w, having || h
+		}
+	}
+
+	switch c := value.(type) {
+	case map[string]interface{}:
+		for key, val := range c {
+			if _, ok := cfg.CustomConditions[key]; !ok && cfg.isAggregateCondition(strings.Split(key, cfg.Separator)[0], val) {
+				having = true
+			} else {
+				where = true
+			}
+		}
+	case *mapConditions:
+		return clausesOf(c.value, cfg)
+	case []map[string]interface{}:
+		for _, item := range c {
+			merge(item)
+		}
+	case []interface{}:
+		if len(c) >= 2 {
+			if _, ok := c[0].(string); ok {
+				// raw condition
+				return true, false
+			}
+		}
+		merge(c...)
+	case *andConditions:
+		merge(c.value...)
+	case *orConditions:
+		merge(c.value...)
+	case *inputConditions:
+		return clausesOf(c.cond, cfg)
+	default:
+		// raw, exists & invalid conditions
+		return true, false
+	}
+	return where, having
+}
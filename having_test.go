@@ -0,0 +1,129 @@
+package gowhere
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlan_Having(t *testing.T) {
+	aggregates := map[string]string{"trip_count": "COUNT(trips.id)", "total_spent": "SUM(trips.budget)"}
+	tests := []struct {
+		name           string
+		plan           func(p *Plan) *Plan
+		wantSQL        string
+		wantVars       []interface{}
+		wantHavingSQL  string
+		wantHavingVars []interface{}
+	}{
+		{
+			name: "split",
+			plan: func(p *Plan) *Plan {
+				return p.Where(map[string]interface{}{"name__icontains": "go", "trip_count__gt": 5}).
+					Where("users.active = ?", true).
+					Not(map[string]interface{}{"total_spent__lt": 100})
+			},
+			wantSQL:        `(LOWER("users"."name") LIKE LOWER(?)) AND (users.active = ?)`,
+			wantVars:       []interface{}{"%go%", true},
+			wantHavingSQL:  `(COUNT(trips.id) > ?) AND NOT (SUM(trips.budget) < ?)`,
+			wantHavingVars: []interface{}{5, 100},
+		},
+		{
+			name: "or group of aggregate fields",
+			plan: func(p *Plan) *Plan {
+				return p.Where(map[string]interface{}{"trip_count__gt": 5}).Or(map[string]interface{}{"total_spent__gte": 1000})
+			},
+			wantSQL:        "",
+			wantVars:       []interface{}{},
+			wantHavingSQL:  `(((COUNT(trips.id) > ?)) OR (SUM(trips.budget) >= ?))`,
+			wantHavingVars: []interface{}{5, 1000},
+		},
+		{
+			name: "reference to aggregate field",
+			plan: func(p *Plan) *Plan {
+				return p.Where(map[string]interface{}{
+					"budget__lt":     Col("total_spent"),
+					"id__gt":         Col("parent_id"),
+					"trip_count__lt": map[string]interface{}{"$col": "max_trips"},
+				})
+			},
+			wantSQL:        `("users"."id" > "users"."parent_id")`,
+			wantVars:       []interface{}{},
+			wantHavingSQL:  `("users"."budget" < SUM(trips.budget) AND COUNT(trips.id) < "users"."max_trips")`,
+			wantHavingVars: []interface{}{},
+		},
+		{
+			name: "no aggregate conditions",
+			plan: func(p *Plan) *Plan {
+				return p.Where(map[string]interface{}{"id": 1})
+			},
+			wantSQL:        `("users"."id" = ?)`,
+			wantVars:       []interface{}{1},
+			wantHavingSQL:  "",
+			wantHavingVars: []interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := tt.plan(WithConfig(Config{Table: "users", AggregateFields: aggregates, Strict: true})).Build()
+			if plan.Error != nil {
+				t.Fatalf("unexpected error: %+v", plan.Error)
+			}
+			if got := plan.SQL(); got != tt.wantSQL {
+				t.Errorf("Plan.SQL() = %v, want %v", got, tt.wantSQL)
+			}
+			if got := plan.Vars(); !reflect.DeepEqual(got, tt.wantVars) {
+				t.Errorf("Plan.Vars() = %v, want %v", got, tt.wantVars)
+			}
+			if got := plan.HavingSQL(); got != tt.wantHavingSQL {
+				t.Errorf("Plan.HavingSQL() = %v, want %v", got, tt.wantHavingSQL)
+			}
+			if got := plan.HavingVars(); !reflect.DeepEqual(got, tt.wantHavingVars) {
+				t.Errorf("Plan.HavingVars() = %v, want %v", got, tt.wantHavingVars)
+			}
+		})
+	}
+}
+
+func TestPlan_Having_Mixed(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     func(p *Plan) *Plan
+		wantPath string
+	}{
+		{
+			name: "or",
+			plan: func(p *Plan) *Plan {
+				return p.Where(map[string]interface{}{"id": 1}).Where([]map[string]interface{}{{"trip_count__gt": 5}, {"name": "x"}})
+			},
+			wantPath: "[1]",
+		},
+		{
+			name: "plan or",
+			plan: func(p *Plan) *Plan {
+				return p.Where(map[string]interface{}{"trip_count__gt": 5}).Or(map[string]interface{}{"name": "x"})
+			},
+			wantPath: "[0]",
+		},
+		{
+			name: "not",
+			plan: func(p *Plan) *Plan {
+				return p.Not(map[string]interface{}{"trip_count__gt": 5, "name": "x"})
+			},
+			wantPath: "[0]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := tt.plan(WithConfig(Config{AggregateFields: map[string]string{"trip_count": "COUNT(trips.id)"}})).Build()
+			warnings := plan.Warnings()
+			if len(warnings) != 1 || warnings[0].Path != tt.wantPath || warnings[0].Reason != ReasonMixedClauses ||
+				!errors.Is(warnings[0], ErrMixedClauses) {
+				t.Fatalf("Plan.Warnings() = %v, want a mixed group at %q", warnings, tt.wantPath)
+			}
+			if plan.HavingSQL() != "" {
+				t.Errorf("Plan.HavingSQL() = %v, want the mixed group skipped", plan.HavingSQL())
+			}
+		})
+	}
+}
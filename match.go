@@ -0,0 +1,554 @@
+package gowhere
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MatchFn represents the function to evaluate the operator against the actual value of the field in memory.
+// The actual value is never NULL, i.e: nil, as NULL doesn't match any operators but isnull, just like in SQL
+type MatchFn func(actual, value interface{}, cfg Config) (bool, error)
+
+// result is the three-valued logic of SQL
+type result int
+
+const (
+	resultTrue result = iota
+	resultFalse
+	resultNull
+)
+
+// not reverses the result, NULL stays NULL
+func (r result) not() result {
+	switch r {
+	case resultTrue:
+		return resultFalse
+	case resultFalse:
+		return resultTrue
+	}
+	return r
+}
+
+// Match evaluates the conditions & scopes of the plan against the row, i.e: a map or a struct, without a database.
+// The fields are located by the column aliases, then the struct tags (db, json, gorm column) or the field names.
+// NULL values (nil, nil pointers & driver.Valuer such as sql.NullString) follow the three-valued logic of SQL.
+// The conditions are rendered by the walker of Build, see Render. Raw conditions, subqueries, relations, expressions &
+// aggregate fields can't be evaluated, returning ErrNotEvaluable. The values created by FromContext are resolved from
+// the context of the last BuildContext, a missing value is always an error.
+// Note: the sorting & the cursor of the plan are ignored
+func (p *Plan) Match(row interface{}) (bool, error) {
+	match, err := p.compile(p.buildContext())
+	if err != nil {
+		return false, err
+	}
+	return match(reflect.ValueOf(row))
+}
+
+// Filter returns a new slice of the same type with the items of the given slice matching the plan, see Match.
+// The conditions are compiled once, e.g: the custom conditions are called once for all items
+func (p *Plan) Filter(rows interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(rows)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("gowhere: expected a slice to filter, got %T", rows)
+	}
+	match, err := p.compile(p.buildContext())
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := reflect.MakeSlice(reflect.SliceOf(rv.Type().Elem()), 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		ok, err := match(rv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			filtered = reflect.Append(filtered, rv.Index(i))
+		}
+	}
+	return filtered.Interface(), nil
+}
+
+// compile renders the scopes & the conditions to the func which evaluates a row. A plan without conditions matches all
+func (p *Plan) compile(ctx context.Context) (func(row reflect.Value) (bool, error), error) {
+	node, err := p.render(ctx, matchRenderer{})
+	if err != nil {
+		return nil, err
+	}
+	return func(row reflect.Value) (bool, error) {
+		if node == nil {
+			return true, nil
+		}
+		res, err := node.(evaluator)(row)
+		return res == resultTrue, err
+	}, nil
+}
+
+// evaluator evaluates a condition against the row
+type evaluator func(row reflect.Value) (result, error)
+
+// matchRenderer renders the conditions to the evaluators, see Plan.Match
+type matchRenderer struct{}
+
+func (matchRenderer) unsupported(what string) error {
+	return notEvaluable("", what)
+}
+
+// Group ties the results of the items by the three-valued logic of SQL
+func (matchRenderer) Group(g *Group) (interface{}, error) {
+	items := make([]evaluator, len(g.Items))
+	for i, item := range g.Items {
+		items[i] = item.(evaluator)
+	}
+	return evaluator(func(row reflect.Value) (result, error) {
+		var res result
+		for i, item := range items {
+			r, err := item(row)
+			if err != nil {
+				return res, err
+			}
+			if i == 0 {
+				res = r
+			} else {
+				res = combine(res, r, !g.Or)
+			}
+		}
+		if g.Not {
+			res = res.not()
+		}
+		return res, nil
+	}), nil
+}
+
+// Predicate evaluates the condition by the Match func of the operator
+func (matchRenderer) Predicate(p *Predicate) (interface{}, error) {
+	cfg, operator, val := p.Config, p.Op, p.Value
+	if operator.Match == nil {
+		return nil, notEvaluable("", fmt.Sprintf("operator %q", p.Operator))
+	}
+	cv, isColumn := val.(ColumnValue)
+
+	return evaluator(func(row reflect.Value) (result, error) {
+		val := val
+		if isColumn {
+			other, err := lookupField(row, cv.Name, cfg)
+			if err != nil {
+				return resultNull, notEvaluable(p.Path, err.Error())
+			}
+			if other == nil {
+				return resultNull, nil
+			}
+			val = other
+		}
+
+		actual, err := lookupField(row, p.Field, cfg)
+		if err != nil {
+			return resultNull, notEvaluable(p.Path, err.Error())
+		}
+		if actual == nil && operator != findOperatorByName("isnull") {
+			return resultNull, nil
+		}
+
+		ok, err := operator.Match(actual, val, *cfg)
+		if err != nil {
+			return resultNull, notEvaluable(p.Path, err.Error())
+		}
+		if ok {
+			return resultTrue, nil
+		}
+		return resultFalse, nil
+	}), nil
+}
+
+// Raw can't be evaluated in memory
+func (matchRenderer) Raw(*Raw) (interface{}, error) {
+	return nil, notEvaluable("", "raw condition")
+}
+
+// combine ties two results by AND or OR operator
+func combine(a, b result, and bool) result {
+	// FALSE wins for AND, TRUE wins for OR, then NULL wins
+	dominant := resultTrue
+	if and {
+		dominant = resultFalse
+	}
+	if a == dominant || b == dominant {
+		return dominant
+	}
+	if a == resultNull || b == resultNull {
+		return resultNull
+	}
+	return a
+}
+
+// notEvaluable returns the error of the condition which can't be evaluated in memory. The path is set by the build
+// if it's empty
+func notEvaluable(path, what string) error {
+	return &ConditionError{Path: path, Reason: ReasonNotEvaluable, Err: &NotEvaluableError{Condition: what}}
+}
+
+// lookupField returns the value of the field in the row, i.e: a map or a struct. NULL values are returned as nil
+func lookupField(row reflect.Value, field string, cfg *Config) (interface{}, error) {
+	names := []string{field}
+	if alias, ok := cfg.ColumnAliases[field]; ok {
+		names = append(names, alias, alias[strings.LastIndex(alias, ".")+1:])
+	}
+
+	for row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface {
+		if row.IsNil() {
+			return nil, errors.New("nil row")
+		}
+		row = row.Elem()
+	}
+
+	switch row.Kind() {
+	case reflect.Map:
+		if row.Type().Key().Kind() != reflect.String {
+			break
+		}
+		for _, name := range names {
+			v := row.MapIndex(reflect.ValueOf(name).Convert(row.Type().Key()))
+			if v.IsValid() {
+				return nullOf(v.Interface()), nil
+			}
+		}
+		// missing keys are NULL
+		return nil, nil
+	case reflect.Struct:
+		for _, name := range names {
+			if v, ok := structField(row, name); ok {
+				return nullOf(v.Interface()), nil
+			}
+		}
+		return nil, fmt.Errorf("field %q not found in %s", field, row.Type())
+	}
+	return nil, fmt.Errorf("unsupported row type %s", row.Type())
+}
+
+// structField finds the field of the struct by the tags (db, json, gorm column), the name or the snake case of the name
+func structField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if fv, ok := structField(v.Field(i), name); ok {
+				return fv, true
+			}
+			continue
+		}
+
+		names := []string{strings.Split(f.Tag.Get("db"), ",")[0], strings.Split(f.Tag.Get("json"), ",")[0]}
+		for _, opt := range strings.Split(f.Tag.Get("gorm"), ";") {
+			if strings.HasPrefix(opt, "column:") {
+				names = append(names, strings.TrimPrefix(opt, "column:"))
+			}
+		}
+		for _, n := range names {
+			if n != "" && n != "-" && n == name {
+				return v.Field(i), true
+			}
+		}
+		if strings.EqualFold(f.Name, name) || snakeCase(f.Name) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// snakeCase converts the name of a struct field to snake case, e.g: CreatedAt => created_at, UserID => user_id
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// nullOf returns the underlying value, or nil for the NULL values: nil pointers & NULL driver.Valuer
+func nullOf(v interface{}) interface{} {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		}
+		value, err := valuer.Value()
+		if err != nil {
+			return v
+		}
+		return value
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// compareValues compares two values, converting them to the same type if possible: numbers, times or strings.
+// Returns false if they're not comparable
+func compareValues(a, b interface{}) (int, bool) {
+	a, b = nullOf(a), nullOf(b)
+	if a == nil || b == nil {
+		return 0, false
+	}
+
+	if ta, ok := toTime(a); ok {
+		if tb, ok := toTime(b); ok {
+			return compareOrdered(ta.UnixNano(), tb.UnixNano()), true
+		}
+	}
+	// the numeric strings are compared as numbers only with a number, e.g: "10" > 9 but "10" < "9"
+	_, aText := toText(a)
+	_, bText := toText(b)
+	if fa, ok := toFloat(a, !bText); ok {
+		if fb, ok := toFloat(b, !aText); ok {
+			switch {
+			case fa < fb:
+				return -1, true
+			case fa > fb:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if ba, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			if ba == bb {
+				return 0, true
+			}
+			if !ba {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+
+	sa, aok := toText(a)
+	sb, bok := toText(b)
+	if aok && bok {
+		return strings.Compare(sa, sb), true
+	}
+	if reflect.DeepEqual(a, b) {
+		return 0, true
+	}
+	return 0, false
+}
+
+func compareOrdered(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// toTime converts the time values & the strings in the common formats to time
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05-07:00", "2006-01-02 15:04:05", "2006-01-02"} {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// toFloat converts the numbers, and optionally the numeric strings, to float64
+func toFloat(v interface{}, numericString bool) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		if numericString {
+			f, err := strconv.ParseFloat(rv.String(), 64)
+			return f, err == nil
+		}
+	}
+	return 0, false
+}
+
+// toText converts the strings & the bytes to string
+func toText(v interface{}) (string, bool) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Kind() == reflect.String:
+		return rv.String(), true
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		return string(rv.Bytes()), true
+	}
+	return "", false
+}
+
+// matchEqual returns the MatchFn of exact & notexact operators. The values which aren't comparable are not equal
+func matchEqual(equal bool) MatchFn {
+	return func(actual, value interface{}, cfg Config) (bool, error) {
+		c, ok := compareValues(actual, value)
+		return (ok && c == 0) == equal, nil
+	}
+}
+
+// matchCompare returns the MatchFn of the comparison operators, e.g: gt
+func matchCompare(fn func(c int) bool) MatchFn {
+	return func(actual, value interface{}, cfg Config) (bool, error) {
+		c, ok := compareValues(actual, value)
+		if !ok {
+			return false, fmt.Errorf("can't compare %T with %T", actual, value)
+		}
+		return fn(c), nil
+	}
+}
+
+// matchLower returns the MatchFn which compares the lower case strings
+func matchLower(equal bool) MatchFn {
+	return func(actual, value interface{}, cfg Config) (bool, error) {
+		return (strings.ToLower(Utils.ToString(actual)) == strings.ToLower(Utils.ToString(value))) == equal, nil
+	}
+}
+
+// matchLike returns the MatchFn of the LIKE operators, with the pattern of the value wrapped by the prefix & suffix
+func matchLike(prefix, suffix string, insensitive bool) MatchFn {
+	return func(actual, value interface{}, cfg Config) (bool, error) {
+		str, pattern := Utils.ToString(actual), prefix+Utils.ToString(value)+suffix
+		if insensitive {
+			str, pattern = strings.ToLower(str), strings.ToLower(pattern)
+		}
+		return like(str, pattern), nil
+	}
+}
+
+// like reports whether the string matches the LIKE pattern: "%" matches any characters, "_" matches a single character,
+// and "\" escapes the next character
+func like(str, pattern string) bool {
+	s, p := []rune(str), []rune(pattern)
+	// the positions to backtrack to, for the last "%"
+	si, pi, starSi, starPi := 0, 0, -1, -1
+	for si < len(s) {
+		if pi < len(p) {
+			switch c := p[pi]; {
+			case c == '%':
+				starPi, starSi = pi, si
+				pi++
+				continue
+			case c == '_':
+				si, pi = si+1, pi+1
+				continue
+			case c == '\\' && pi+1 < len(p):
+				if p[pi+1] == s[si] {
+					si, pi = si+1, pi+2
+					continue
+				}
+			case c == s[si]:
+				si, pi = si+1, pi+1
+				continue
+			}
+		}
+		if starPi < 0 {
+			return false
+		}
+		starSi++
+		si, pi = starSi, starPi+1
+	}
+	for pi < len(p) && p[pi] == '%' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// matchIn returns the MatchFn of in & notin operators
+func matchIn(in bool) MatchFn {
+	return func(actual, value interface{}, cfg Config) (bool, error) {
+		list := reflect.ValueOf(Utils.ToSlice(value))
+		for i := 0; i < list.Len(); i++ {
+			if c, ok := compareValues(actual, list.Index(i).Interface()); ok && c == 0 {
+				return in, nil
+			}
+		}
+		return !in, nil
+	}
+}
+
+// matchDate compares the date of the actual value, in the location if the time zone is converted
+func matchDate(actual, value interface{}, cfg Config) (bool, error) {
+	return dateOf(actual, cfg) == Utils.ToDate(value), nil
+}
+
+// matchYear compares the year of the actual value
+func matchYear(actual, value interface{}, cfg Config) (bool, error) {
+	year, err := strconv.Atoi(Utils.ToString(value))
+	if err != nil {
+		return false, err
+	}
+	date := dateOf(actual, cfg)
+	return len(date) >= 4 && date[:4] == fmt.Sprintf("%04d", year), nil
+}
+
+// matchBetween checks the actual value is within the range, inclusively
+func matchBetween(actual, value interface{}, cfg Config) (bool, error) {
+	from, to, _ := rangeValues(value)
+	low, lok := compareValues(actual, from)
+	high, hok := compareValues(actual, to)
+	if !lok || !hok {
+		return false, fmt.Errorf("can't compare %T with the range", actual)
+	}
+	return low >= 0 && high <= 0, nil
+}
+
+// matchDateBetween checks the date of the actual value is within the range of dates, inclusively
+func matchDateBetween(actual, value interface{}, cfg Config) (bool, error) {
+	from, to, _ := rangeValues(value)
+	date := dateOf(actual, cfg)
+	return date >= Utils.ToDate(from) && date <= Utils.ToDate(to), nil
+}
+
+// matchNull checks whether the actual value is NULL
+func matchNull(actual, value interface{}, cfg Config) (bool, error) {
+	if null, ok := value.(bool); ok && !null {
+		return actual != nil, nil
+	}
+	return actual == nil, nil
+}
+
+// dateOf returns the date of the actual value, i.e: "2006-01-02"
+func dateOf(actual interface{}, cfg Config) string {
+	t, ok := toTime(actual)
+	if !ok {
+		return Utils.ToDate(actual)
+	}
+	if cfg.ConvertTimeZone && cfg.Location != nil {
+		t = t.In(cfg.Location)
+	}
+	return t.Format("2006-01-02")
+}
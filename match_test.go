@@ -0,0 +1,149 @@
+package gowhere
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type matchTrip struct {
+	ID        int
+	Name      string         `db:"trip_name"`
+	Budget    float64        `json:"budget"`
+	Note      sql.NullString `gorm:"column:remark"`
+	CreatedAt time.Time
+	EndedAt   *time.Time
+	Spent     float64
+}
+
+func TestPlan_Match(t *testing.T) {
+	created := time.Date(2020, 3, 15, 23, 30, 0, 0, time.UTC)
+	trip := matchTrip{ID: 7, Name: "Gopher's 100% trip", Budget: 1000, CreatedAt: created, Spent: 1200}
+	row := map[string]interface{}{"id": 7, "name": "Gopher", "budget": 1000, "note": nil, "created_at": "2020-03-15 23:30:00"}
+
+	tests := []struct {
+		name string
+		plan *Plan
+		want bool
+	}{
+		{"exact", Where(map[string]interface{}{"id": 7, "trip_name": "Gopher's 100% trip"}), true},
+		{"exact by field name", Where(map[string]interface{}{"name": "Gopher's 100% trip", "created_at": created}), true},
+		{"aliases", WithConfig(Config{ColumnAliases: map[string]string{"title": "t.trip_name"}}).Where(map[string]interface{}{"title__istartswith": "gopher"}), true},
+		{"iexact", Where(map[string]interface{}{"name__iexact": "GOPHER'S 100% TRIP"}), true},
+		{"notexact", Where(map[string]interface{}{"id__notexact": 7}), false},
+		{"gt", Where(map[string]interface{}{"budget__gt": 999}), true},
+		{"lte", Where(map[string]interface{}{"budget__lte": 999.5}), false},
+		{"like wildcard", Where(map[string]interface{}{"name__contains": "100%"}), true},
+		{"like underscore", Where(map[string]interface{}{"name__endswith": "_rip"}), true},
+		{"like case sensitive", Where(map[string]interface{}{"name__contains": "GOPHER"}), false},
+		{"icontains", Where(map[string]interface{}{"name__icontains": "GOPHER"}), true},
+		{"in", Where(map[string]interface{}{"id": []int{1, 7}}), true},
+		{"notin", Where(map[string]interface{}{"id__notin": []int{1, 7}}), false},
+		{"date", Where(map[string]interface{}{"createdat__date": "2020-03-15"}), true},
+		{"date in location", WithConfig(Config{Location: time.FixedZone("UTC+7", 7*3600), ConvertTimeZone: true}).Where(map[string]interface{}{"created_at__date": "2020-03-16"}), true},
+		{"year", Where(map[string]interface{}{"created_at__year": 2020}), true},
+		{"between", Where(map[string]interface{}{"created_at__between": []string{"2020-03-01", "2020-04-01"}}), true},
+		{"datebetween", Where(map[string]interface{}{"created_at__datebetween": []string{"2020-03-01", "2020-03-14"}}), false},
+		{"isnull", Where(map[string]interface{}{"ended_at__isnull": true, "remark__isnull": true}), true},
+		{"null doesn't match", Where(map[string]interface{}{"ended_at__gt": "2020-01-01"}), false},
+		{"not null doesn't match", Where(map[string]interface{}{}).Not(map[string]interface{}{"ended_at__gt": "2020-01-01"}), false},
+		{"column reference", Where(map[string]interface{}{"spent__gt": Col("budget")}), true},
+		{"or", Where(map[string]interface{}{"id": 1}).Or(map[string]interface{}{"budget__gte": 1000}), true},
+		{"or with null", Where(map[string]interface{}{"ended_at__gt": "2020-01-01"}).Or(map[string]interface{}{"id": 7}), true},
+		{"not", Where(map[string]interface{}{}).Not(map[string]interface{}{"id": 7}), false},
+		{"slice of or", Where([]interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 7}}), true},
+		{"scope", Where(map[string]interface{}{"id": 7}).Scope(map[string]interface{}{"budget__lt": 10}), false},
+		{"skipped invalid conditions", Where(map[string]interface{}{"id__unknown": 1}).Where(1), true},
+		{"empty", Where(map[string]interface{}{}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.plan.Match(trip)
+			if err != nil {
+				t.Fatalf("Plan.Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Plan.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got, err := Where(map[string]interface{}{"name__startswith": "Go", "note__isnull": true, "missing__isnull": true, "created_at__date": "2020-03-15"}).Match(row); err != nil || !got {
+		t.Errorf("Plan.Match() of map = %v, %v, want true", got, err)
+	}
+}
+
+func TestPlan_Match_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		plan *Plan
+		want error
+	}{
+		{"raw", Where("id = ?", 1), ErrNotEvaluable},
+		{"exists", Where(Exists(WithConfig(Config{Table: "trips"}))), ErrNotEvaluable},
+		{"expression", Where(map[string]interface{}{"budget__gt": Expr("1 + 1")}), ErrNotEvaluable},
+		{"unknown field", Where(map[string]interface{}{"unknown": 1}), ErrNotEvaluable},
+		{"strict", WithConfig(Config{Strict: true}).Where(map[string]interface{}{"id__unknown": 1}), ErrUnknownOperator},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.plan.Match(matchTrip{}); !errors.Is(err, tt.want) {
+				t.Errorf("Plan.Match() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlan_Filter(t *testing.T) {
+	trips := []matchTrip{{ID: 1, Budget: 10}, {ID: 2, Budget: 20}, {ID: 3, Budget: 30}}
+	got, err := Where(map[string]interface{}{"budget__gte": 20}).Filter(trips)
+	if err != nil {
+		t.Fatalf("Plan.Filter() error = %v", err)
+	}
+	if want := []matchTrip{{ID: 2, Budget: 20}, {ID: 3, Budget: 30}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Plan.Filter() = %v, want %v", got, want)
+	}
+	if _, err := Where(map[string]interface{}{}).Filter(trips[0]); err == nil {
+		t.Errorf("Plan.Filter() of non-slice should fail")
+	}
+
+	// the custom conditions are called once for all items
+	calls := 0
+	cfg := Config{CustomConditions: map[string]CustomConditionFn{
+		"cheap": func(key string, val interface{}, cfg *Config) interface{} {
+			calls++
+			return map[string]interface{}{"budget__lt": val}
+		},
+	}}
+	got, err = WithConfig(cfg).Where(map[string]interface{}{"cheap": 30}).Filter(trips)
+	if err != nil {
+		t.Fatalf("Plan.Filter() error = %v", err)
+	}
+	if want := trips[:2]; !reflect.DeepEqual(got, want) || calls != 1 {
+		t.Errorf("Plan.Filter() = %v with %d calls, want %v with 1 call", got, calls, want)
+	}
+}
+
+func TestLike(t *testing.T) {
+	tests := []struct {
+		str, pattern string
+		want         bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "a%", true},
+		{"abc", "%c", true},
+		{"abc", "a_c", true},
+		{"abc", "a_", false},
+		{"a%c", `a\%c`, true},
+		{"abc", `a\%c`, false},
+		{"", "%", true},
+		{"aXbXc", "%b%c", true},
+	}
+	for _, tt := range tests {
+		if got := like(tt.str, tt.pattern); got != tt.want {
+			t.Errorf("like(%q, %q) = %v, want %v", tt.str, tt.pattern, got, tt.want)
+		}
+	}
+}
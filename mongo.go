@@ -0,0 +1,181 @@
+package gowhere
+
+import (
+	"regexp"
+	"time"
+)
+
+// mongoRenderer renders the conditions to MongoDB query documents
+type mongoRenderer struct{}
+
+// ToMongo returns the MongoDB query document of the conditions & scopes, e.g: {"name": {"$regex": "^go"}}, as plain maps
+// which are accepted by the drivers. The fields are mapped by the column aliases, e.g: "profile.name" for nested fields.
+// Like Build, the invalid conditions are skipped in non-strict mode, and Plan.Error is returned otherwise.
+// The values created by FromContext are resolved from the context of the last BuildContext, see Render.
+// Raw conditions, subqueries, relations, expressions, aggregate fields & custom operators return ErrNotRenderable.
+// Returns an empty document if there are no conditions
+func (p *Plan) ToMongo() (map[string]interface{}, error) {
+	query, err := p.Render(mongoRenderer{})
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		return map[string]interface{}{}, nil
+	}
+	return query.(map[string]interface{}), nil
+}
+
+func (mongoRenderer) target() string {
+	return "MongoDB"
+}
+
+func (r mongoRenderer) Group(g *Group) (interface{}, error) {
+	return tieDocs(g, r.and, r.or, r.not), nil
+}
+
+func (r mongoRenderer) Raw(*Raw) (interface{}, error) {
+	return nil, &NotRenderableError{Condition: "raw condition", Target: r.target()}
+}
+
+// and merges the documents if they have distinct keys, e.g: {"a": 1, "b": 2}, otherwise uses $and
+func (mongoRenderer) and(docs []map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, doc := range docs {
+		for key, val := range doc {
+			if _, ok := merged[key]; ok {
+				return map[string]interface{}{"$and": docs}
+			}
+			merged[key] = val
+		}
+	}
+	return merged
+}
+
+func (mongoRenderer) or(docs []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"$or": docs}
+}
+
+func (mongoRenderer) not(doc map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"$nor": []map[string]interface{}{doc}}
+}
+
+func (r mongoRenderer) Predicate(pred *Predicate) (interface{}, error) {
+	cfg := pred.Config
+	field := pred.Field
+	if alias, ok := cfg.ColumnAliases[field]; ok {
+		field = alias
+	}
+	doc := func(cond interface{}) map[string]interface{} {
+		return map[string]interface{}{field: cond}
+	}
+	regex := func(pattern string, insensitive bool) map[string]interface{} {
+		re := map[string]interface{}{"$regex": pattern}
+		if insensitive {
+			re["$options"] = "i"
+		}
+		return re
+	}
+
+	unsupported := func(what string) (interface{}, error) {
+		return nil, &NotRenderableError{Condition: what, Target: r.target()}
+	}
+
+	name := pred.Operator
+	value := pred.Value
+	if cv, ok := value.(ColumnValue); ok {
+		// compare with another field of the same document
+		other := cv.Name
+		if alias, ok := cfg.ColumnAliases[other]; ok {
+			other = alias
+		}
+		ops := map[string]string{"exact": "$eq", "notexact": "$ne", "gt": "$gt", "gte": "$gte", "lt": "$lt", "lte": "$lte"}
+		if op, ok := ops[name]; ok {
+			return map[string]interface{}{"$expr": map[string]interface{}{op: []interface{}{"$" + field, "$" + other}}}, nil
+		}
+		return unsupported("column reference of operator " + name)
+	}
+
+	quoted := regexp.QuoteMeta(Utils.ToString(value))
+	switch name {
+	case "exact":
+		return doc(map[string]interface{}{"$eq": value}), nil
+	case "notexact":
+		return doc(map[string]interface{}{"$ne": value}), nil
+	case "gt", "gte", "lt", "lte":
+		return doc(map[string]interface{}{"$" + name: value}), nil
+	case "iexact":
+		return doc(regex("^"+quoted+"$", true)), nil
+	case "inotexact":
+		return doc(map[string]interface{}{"$not": regex("^"+quoted+"$", true)}), nil
+	case "startswith", "istartswith":
+		return doc(regex("^"+quoted, name == "istartswith")), nil
+	case "endswith", "iendswith":
+		return doc(regex(quoted+"$", name == "iendswith")), nil
+	case "contains", "icontains":
+		return doc(regex(quoted, name == "icontains")), nil
+	case "in":
+		return doc(map[string]interface{}{"$in": Utils.ToSlice(value)}), nil
+	case "notin":
+		return doc(map[string]interface{}{"$nin": Utils.ToSlice(value)}), nil
+	case "isnull":
+		if null, ok := value.(bool); ok && !null {
+			return doc(map[string]interface{}{"$exists": true, "$ne": nil}), nil
+		}
+		// matches both the missing fields & the null values
+		return doc(nil), nil
+	case "between":
+		from, to, _ := rangeValues(value)
+		return doc(map[string]interface{}{"$gte": from, "$lte": to}), nil
+	case "date", "year", "datebetween":
+		from, to, ok := dateRange(name, value, cfg.Location)
+		if !ok {
+			return unsupported("value of operator " + name)
+		}
+		return doc(map[string]interface{}{"$gte": from, "$lt": to}), nil
+	}
+	return unsupported("operator " + name)
+}
+
+// tieDocs ties the documents of the group by the given funcs of the document renderers
+func tieDocs(g *Group, and, or func([]map[string]interface{}) map[string]interface{}, not func(map[string]interface{}) map[string]interface{}) map[string]interface{} {
+	docs := make([]map[string]interface{}, len(g.Items))
+	for i, item := range g.Items {
+		docs[i] = item.(map[string]interface{})
+	}
+
+	var doc map[string]interface{}
+	switch {
+	case len(docs) == 1:
+		doc = docs[0]
+	case g.Or:
+		doc = or(docs)
+	default:
+		doc = and(docs)
+	}
+	if g.Not {
+		doc = not(doc)
+	}
+	return doc
+}
+
+// dateRange returns the half-open range of the date operators: date, year & datebetween
+func dateRange(name string, value interface{}, loc *time.Location) (time.Time, time.Time, bool) {
+	switch name {
+	case "date":
+		from, ok := dayOf(value, loc)
+		return from, from.AddDate(0, 0, 1), ok
+	case "year":
+		if loc == nil {
+			loc = time.UTC
+		}
+		year, ok := toFloat(value, true)
+		from := time.Date(int(year), time.January, 1, 0, 0, 0, 0, loc)
+		return from, from.AddDate(1, 0, 0), ok
+	case "datebetween":
+		from, to, _ := rangeValues(value)
+		fromDay, fromOK := dayOf(from, loc)
+		toDay, toOK := dayOf(to, loc)
+		return fromDay, toDay.AddDate(0, 0, 1), fromOK && toOK
+	}
+	return time.Time{}, time.Time{}, false
+}
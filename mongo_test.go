@@ -0,0 +1,117 @@
+package gowhere
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlan_ToMongo(t *testing.T) {
+	day := time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		plan *Plan
+		want map[string]interface{}
+	}{
+		{
+			name: "operators",
+			plan: WithConfig(Config{ColumnAliases: map[string]string{"name": "profile.name"}}).Where(map[string]interface{}{
+				"name__istartswith": "go.",
+				"budget__gte":       100,
+				"id":                []int{1, 2},
+				"deleted_at":        nil,
+				"tag__notexact":     "x",
+			}),
+			want: map[string]interface{}{
+				"profile.name": map[string]interface{}{"$regex": `^go\.`, "$options": "i"},
+				"budget":       map[string]interface{}{"$gte": 100},
+				"id":           map[string]interface{}{"$in": []int{1, 2}},
+				"deleted_at":   nil,
+				"tag":          map[string]interface{}{"$ne": "x"},
+			},
+		},
+		{
+			name: "same field",
+			plan: Where(map[string]interface{}{"budget__gt": 1}).Where(map[string]interface{}{"budget__lt": 9}),
+			want: map[string]interface{}{"$and": []map[string]interface{}{
+				{"budget": map[string]interface{}{"$gt": 1}},
+				{"budget": map[string]interface{}{"$lt": 9}},
+			}},
+		},
+		{
+			name: "or & not",
+			plan: Where(map[string]interface{}{"id": 1}).Or(map[string]interface{}{"name__contains": "a"}).
+				Not(map[string]interface{}{"ended_at__isnull": false}),
+			want: map[string]interface{}{
+				"$or": []map[string]interface{}{
+					{"id": map[string]interface{}{"$eq": 1}},
+					{"name": map[string]interface{}{"$regex": "a"}},
+				},
+				"$nor": []map[string]interface{}{{"ended_at": map[string]interface{}{"$exists": true, "$ne": nil}}},
+			},
+		},
+		{
+			name: "dates",
+			plan: Where(map[string]interface{}{"created_at__date": "2020-03-15", "paid_at__between": []interface{}{1, 2}}),
+			want: map[string]interface{}{
+				"created_at": map[string]interface{}{"$gte": day, "$lt": day.AddDate(0, 0, 1)},
+				"paid_at":    map[string]interface{}{"$gte": 1, "$lte": 2},
+			},
+		},
+		{
+			name: "column reference & scope",
+			plan: Where(map[string]interface{}{"spent__gt": Col("budget")}).Scope(map[string]interface{}{"tenant_id": 1}),
+			want: map[string]interface{}{
+				"tenant_id": map[string]interface{}{"$eq": 1},
+				"$expr":     map[string]interface{}{"$gt": []interface{}{"$spent", "$budget"}},
+			},
+		},
+		{
+			name: "context scope",
+			plan: Where(map[string]interface{}{"id": 1}).Scope(map[string]interface{}{"tenant_id": FromContext(ctxKey("tenant_id"))}).
+				BuildContext(context.WithValue(context.Background(), ctxKey("tenant_id"), 7)),
+			want: map[string]interface{}{
+				"tenant_id": map[string]interface{}{"$eq": 7},
+				"id":        map[string]interface{}{"$eq": 1},
+			},
+		},
+		{
+			name: "empty",
+			plan: Where(map[string]interface{}{"id__unknown": 1}),
+			want: map[string]interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.plan.ToMongo()
+			if err != nil {
+				t.Fatalf("Plan.ToMongo() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Plan.ToMongo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlan_ToMongo_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		plan *Plan
+		want error
+	}{
+		{"raw", Where("id = ?", 1), ErrNotRenderable},
+		{"column reference of year", Where(map[string]interface{}{"id__year": Col("x")}), ErrNotRenderable},
+		{"strict", WithConfig(Config{Strict: true}).Where(map[string]interface{}{"id__unknown": 1}), ErrUnknownOperator},
+		{"missing context value", Where(map[string]interface{}{"id": 1}).Scope(map[string]interface{}{"tenant_id": FromContext(ctxKey("tenant_id"))}), ErrInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.plan.ToMongo(); !errors.Is(err, tt.want) {
+				t.Errorf("Plan.ToMongo() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
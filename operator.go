@@ -1,8 +1,11 @@
 package gowhere
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,6 +15,10 @@ type CustomBuildFn func(field string, value interface{}, cfg Config) (string, []
 // ModValueFn represents the function to modify only the value before actually build the SQL
 type ModValueFn func(value interface{}) interface{}
 
+// ValidateFn represents the function to check the given value before build the SQL.
+// Returned error is reported as InvalidValueError
+type ValidateFn func(value interface{}) error
+
 // Operator represents an alias for the SQL operator
 type Operator struct {
 	// Reference to an existing operator
@@ -23,12 +30,37 @@ type Operator struct {
 	Template string
 	// The function to build the SQL condition in your own way. Ignored if AliasOf is provided, ignores Operator & Template.
 	CustomBuild CustomBuildFn
+	// The function to build the SQL condition which can use the index of the column, e.g: a half-open range instead of
+	// DATE(column). Used instead of the others if the SargableDates config is enabled. Return empty string to fall back
+	SargableBuild CustomBuildFn
 	// Instead of customize the whole build func, you probably only want to modify the value a litle bit
 	ModValue ModValueFn
+	// The function to reject the value which doesn't fit the operator
+	Validate ValidateFn
+	// Whether the operator compares time values, so the relative time values such as "now-7d" are resolved
+	TimeValue bool
+	// The function to evaluate the condition in memory, see Plan.Match. Required by Match for the custom operators
+	Match MatchFn
+	// The description of the operator & the JSON Schema of its value, see Plan.JSONSchema. Optional for the custom
+	// operators, which accept any value by default
+	Description string
+	Schema      map[string]interface{}
+
+	// whether the operator takes a range of two values, so the relative range such as "-1w..now" is resolved
+	ranged bool
+	// the SQL of an empty list, e.g: nothing is excluded by notin, while NOT IN (NULL) would match no rows
+	emptyList string
 }
 
 // Build returns the SQL string & vars for a single condition.
+// Inline values, such as the column references, replace the "?" placeholder and are not modified by ModValue.
 func (o *Operator) Build(field string, value interface{}, cfg *Config) (string, []interface{}) {
+	if o.SargableBuild != nil && cfg.SargableDates {
+		if sql, vars := o.SargableBuild(field, value, *cfg); sql != "" {
+			return sql, vars
+		}
+	}
+
 	if o.CustomBuild != nil {
 		return o.CustomBuild(field, value, *cfg)
 	}
@@ -43,33 +75,45 @@ func (o *Operator) Build(field string, value interface{}, cfg *Config) (string,
 		template = "%s %s ?"
 	}
 
+	if iv, ok := value.(inlineValue); ok {
+		sql, vars := iv.inline(cfg)
+		template = strings.Replace(template, "?", escapeFormat(sql), 1)
+		return fmt.Sprintf(template, field, operator), vars
+	}
+
 	if o.ModValue != nil {
 		value = o.ModValue(value)
 	}
+	if o.emptyList != "" {
+		if rv := reflect.ValueOf(value); (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Len() == 0 {
+			return o.emptyList, []interface{}{}
+		}
+	}
 
-	return fmt.Sprintf(template, field, operator), []interface{}{Utils.ToSQLVar(value)}
+	return fmt.Sprintf(template, field, operator), []interface{}{timeVar(value, cfg)}
 }
 
 var (
-	defaultOperator = &Operator{}
+	defaultOperator = &Operator{Match: matchEqual(true)}
 
 	// OperatorsList defines the list of built-in operators
 	OperatorsList = map[string]*Operator{
 		"exact":     defaultOperator,
-		"iexact":    &Operator{Template: "LOWER(%s) %s LOWER(?)"},
-		"notexact":  &Operator{Operator: "<>"},
-		"inotexact": &Operator{Operator: "<>", Template: "LOWER(%s) %s LOWER(?)"},
+		"iexact":    &Operator{Template: "LOWER(%s) %s LOWER(?)", Match: matchLower(true)},
+		"notexact":  &Operator{Operator: "<>", Match: matchEqual(false)},
+		"inotexact": &Operator{Operator: "<>", Template: "LOWER(%s) %s LOWER(?)", Match: matchLower(false)},
 
-		"gt":  &Operator{Operator: ">"},
-		"lt":  &Operator{Operator: "<"},
-		"gte": &Operator{Operator: ">="},
-		"lte": &Operator{Operator: "<="},
+		"gt":  &Operator{Operator: ">", Match: matchCompare(func(c int) bool { return c > 0 })},
+		"lt":  &Operator{Operator: "<", Match: matchCompare(func(c int) bool { return c < 0 })},
+		"gte": &Operator{Operator: ">=", Match: matchCompare(func(c int) bool { return c >= 0 })},
+		"lte": &Operator{Operator: "<=", Match: matchCompare(func(c int) bool { return c <= 0 })},
 
 		"startswith": &Operator{
 			Operator: "LIKE",
 			ModValue: func(value interface{}) interface{} {
 				return Utils.ToString(value) + "%"
 			},
+			Match: matchLike("", "%", false),
 		},
 		"istartswith": &Operator{
 			Operator: "LIKE",
@@ -77,12 +121,14 @@ var (
 			ModValue: func(value interface{}) interface{} {
 				return Utils.ToString(value) + "%"
 			},
+			Match: matchLike("", "%", true),
 		},
 		"endswith": &Operator{
 			Operator: "LIKE",
 			ModValue: func(value interface{}) interface{} {
 				return "%" + Utils.ToString(value)
 			},
+			Match: matchLike("%", "", false),
 		},
 		"iendswith": &Operator{
 			Operator: "LIKE",
@@ -90,12 +136,14 @@ var (
 			ModValue: func(value interface{}) interface{} {
 				return "%" + Utils.ToString(value)
 			},
+			Match: matchLike("%", "", true),
 		},
 		"contains": &Operator{
 			Operator: "LIKE",
 			ModValue: func(value interface{}) interface{} {
 				return "%" + Utils.ToString(value) + "%"
 			},
+			Match: matchLike("%", "%", false),
 		},
 		"icontains": &Operator{
 			Operator: "LIKE",
@@ -103,6 +151,7 @@ var (
 			ModValue: func(value interface{}) interface{} {
 				return "%" + Utils.ToString(value) + "%"
 			},
+			Match: matchLike("%", "%", true),
 		},
 		"in": &Operator{
 			Operator: "IN",
@@ -110,33 +159,64 @@ var (
 			ModValue: func(value interface{}) interface{} {
 				return Utils.ToSlice(value)
 			},
+			Match: matchIn(true),
+		},
+		"notin": &Operator{
+			Operator: "NOT IN",
+			Template: "%s %s (?)",
+			ModValue: func(value interface{}) interface{} {
+				return Utils.ToSlice(value)
+			},
+			Match:     matchIn(false),
+			emptyList: "1 = 1",
 		},
 		"date": &Operator{
-			Template: "DATE(%s) %s ?",
+			TimeValue: true,
+			Template:  "DATE(%s) %s ?",
 			ModValue: func(value interface{}) interface{} {
 				return Utils.ToDate(value)
 			},
+			SargableBuild: func(field string, value interface{}, cfg Config) (string, []interface{}) {
+				from, ok := dayOf(value, cfg.Location)
+				if !ok {
+					return "", []interface{}{}
+				}
+				return buildHalfOpen(field, from, from.AddDate(0, 0, 1), cfg.Location)
+			},
+			Match: matchDate,
+		},
+		"year": &Operator{
+			TimeValue: true,
+			Template:  "EXTRACT(YEAR FROM %s) %s ?",
+			SargableBuild: func(field string, value interface{}, cfg Config) (string, []interface{}) {
+				year, err := strconv.Atoi(Utils.ToString(value))
+				if err != nil {
+					return "", []interface{}{}
+				}
+				loc := cfg.Location
+				if loc == nil {
+					loc = time.UTC
+				}
+				from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+				return buildHalfOpen(field, from, from.AddDate(1, 0, 0), cfg.Location)
+			},
+			Match: matchYear,
 		},
 		"between": &Operator{
+			ranged:   true,
+			Validate: validateRange,
 			CustomBuild: func(field string, value interface{}, cfg Config) (string, []interface{}) {
-				var from interface{}
-				var to interface{}
-
-				if vi, ok := value.([]interface{}); ok && len(vi) >= 2 {
-					from = vi[0]
-					to = vi[1]
-				} else if vs, ok := value.([]string); ok && len(vs) >= 2 {
-					from = vs[0]
-					to = vs[1]
-				} else if vt, ok := value.([]time.Time); ok && len(vt) >= 2 {
-					from = vt[0]
-					to = vt[1]
-				} else {
+				from, to, ok := rangeValues(value)
+				if !ok {
 					return "", []interface{}{}
 				}
 
-				return fmt.Sprintf("%s BETWEEN ? AND ?", field), []interface{}{Utils.ToDateTime(from), Utils.ToDateTime(to)}
+				toVar := func(value interface{}) interface{} { return timeVar(value, &cfg) }
+				fromSQL, fromVars := bindVar(from, &cfg, toVar)
+				toSQL, toVars := bindVar(to, &cfg, toVar)
+				return fmt.Sprintf("%s BETWEEN %s AND %s", field, fromSQL, toSQL), append(fromVars, toVars...)
 			},
+			Match: matchBetween,
 		},
 		"isnull": &Operator{
 			CustomBuild: func(field string, value interface{}, cfg Config) (string, []interface{}) {
@@ -146,31 +226,112 @@ var (
 				}
 				return fmt.Sprintf("%s %s", field, operator), []interface{}{}
 			},
+			Match: matchNull,
 		},
 		"datebetween": &Operator{
+			TimeValue: true,
+			ranged:    true,
+			Validate:  validateRange,
 			CustomBuild: func(field string, value interface{}, cfg Config) (string, []interface{}) {
-				var from interface{}
-				var to interface{}
-
-				if vi, ok := value.([]interface{}); ok && len(vi) >= 2 {
-					from = vi[0]
-					to = vi[1]
-				} else if vs, ok := value.([]string); ok && len(vs) >= 2 {
-					from = vs[0]
-					to = vs[1]
-				} else if vt, ok := value.([]time.Time); ok && len(vt) >= 2 {
-					from = vt[0]
-					to = vt[1]
-				} else {
+				from, to, ok := rangeValues(value)
+				if !ok {
 					return "", []interface{}{}
 				}
 
-				return fmt.Sprintf("DATE(%s) BETWEEN ? AND ?", field), []interface{}{Utils.ToDate(from), Utils.ToDate(to)}
+				fromSQL, fromVars := bindVar(from, &cfg, toDate)
+				toSQL, toVars := bindVar(to, &cfg, toDate)
+				return fmt.Sprintf("DATE(%s) BETWEEN %s AND %s", field, fromSQL, toSQL), append(fromVars, toVars...)
+			},
+			SargableBuild: func(field string, value interface{}, cfg Config) (string, []interface{}) {
+				from, to, _ := rangeValues(value)
+				fromDay, ok := dayOf(from, cfg.Location)
+				if !ok {
+					return "", []interface{}{}
+				}
+				toDay, ok := dayOf(to, cfg.Location)
+				if !ok {
+					return "", []interface{}{}
+				}
+				return buildHalfOpen(field, fromDay, toDay.AddDate(0, 0, 1), cfg.Location)
 			},
+			Match: matchDateBetween,
 		},
 	}
 )
 
+// timeVar converts the value by Utils.ToSQLVar, except the times in the Location config which keep their offset, so
+// they're compared correctly regardless of the session time zone. Unless the column is converted to the location,
+// which is compared with the wall clock of the location. Without the Location config, the times are kept naive
+func timeVar(value interface{}, cfg *Config) interface{} {
+	t, ok := value.(time.Time)
+	if tp, isPtr := value.(*time.Time); isPtr && tp != nil {
+		t, ok = *tp, true
+	}
+	if !ok || cfg.Location == nil || cfg.naiveTimes {
+		return Utils.ToSQLVar(value)
+	}
+	return t.Format("2006-01-02 15:04:05.999999999-07:00")
+}
+
+// toDate wraps Utils.ToDate to be used as the converter of bindVar
+func toDate(value interface{}) interface{} {
+	return Utils.ToDate(value)
+}
+
+// dayOf returns the start of the day of the given time or date string. Date strings are parsed in the given location,
+// or UTC if not given. Returns false for the other values, e.g: inline values
+func dayOf(value interface{}, loc *time.Location) (time.Time, bool) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	switch v := value.(type) {
+	case time.Time:
+		y, m, d := v.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, v.Location()), true
+	case *time.Time:
+		if v != nil {
+			return dayOf(*v, loc)
+		}
+	case string:
+		if len(v) >= 10 {
+			if t, err := time.ParseInLocation("2006-01-02", v[:10], loc); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// buildHalfOpen builds the range condition: from <= column < to. The bounds are dates if the location is not given,
+// otherwise they're datetimes with the offset of the location, so the range is correct regardless of the session time zone
+func buildHalfOpen(field string, from, to time.Time, loc *time.Location) (string, []interface{}) {
+	layout := "2006-01-02"
+	if loc != nil {
+		layout = "2006-01-02 15:04:05-07:00"
+	}
+	return fmt.Sprintf("(%s >= ? AND %s < ?)", field, field), []interface{}{from.Format(layout), to.Format(layout)}
+}
+
+// rangeValues returns the lower & upper bounds of the value for range operators, which must have exactly two items
+func rangeValues(value interface{}) (from interface{}, to interface{}, ok bool) {
+	if vi, ok := value.([]interface{}); ok && len(vi) == 2 {
+		return vi[0], vi[1], true
+	} else if vs, ok := value.([]string); ok && len(vs) == 2 {
+		return vs[0], vs[1], true
+	} else if vt, ok := value.([]time.Time); ok && len(vt) == 2 {
+		return vt[0], vt[1], true
+	}
+	return nil, nil, false
+}
+
+// validateRange requires the value to have exactly two items for range operators
+func validateRange(value interface{}) error {
+	if _, _, ok := rangeValues(value); !ok {
+		return errors.New("expected a slice of two items")
+	}
+	return nil
+}
+
 func findOperatorByName(name string) *Operator {
 	if op, ok := OperatorsList[name]; ok {
 		if op.AliasOf != "" {
@@ -188,6 +349,10 @@ func findOperatorByValue(value interface{}) *Operator {
 		return findOperatorByName("isnull")
 	}
 
+	if _, ok := toSubquery(value); ok {
+		return findOperatorByName("in")
+	}
+
 	vt := reflect.TypeOf(value)
 	if vt.Kind() == reflect.Array || vt.Kind() == reflect.Slice {
 		return findOperatorByName("in")
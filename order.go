@@ -0,0 +1,193 @@
+package gowhere
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Nulls ordering of Sort
+const (
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
+// Sort represents the sorting of a single field
+type Sort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+	// Either NullsFirst or NullsLast. Default to empty which keeps the default ordering of the database
+	Nulls string `json:"nulls,omitempty"`
+}
+
+// NullsOrderer is the optional interface for a dialect to render the ordering of NULL values.
+// The dialects without it use the standard syntax: column ASC NULLS FIRST
+type NullsOrderer interface {
+	// OrderNulls returns the ORDER BY item of the column with given direction, i.e "ASC" or "DESC"
+	OrderNulls(column, direction string, nullsFirst bool) string
+}
+
+// OrderBy adds the sorting specification to the current Plan, which is rendered by OrderSQL. Accepted forms:
+//
+//	"-created_at,name"                 // "-" prefix for descending order
+//	"-paid_at:nulls_last"              // ":nulls_first" or ":nulls_last" suffix for the ordering of NULL values
+//	[]string{"-created_at", "name"}
+//	[]byte(`["-created_at", {"field": "name", "desc": false, "nulls": "last"}]`)
+//	[]gowhere.Sort{{Field: "created_at", Desc: true}}
+//
+// The fields are mapped by the column aliases and checked against the allowed fields, just like the conditions.
+func (p *Plan) OrderBy(spec interface{}) *Plan {
+	p.orders = append(p.orders, spec)
+	p.built = false
+	return p
+}
+
+// OrderSQL returns the built ORDER BY clause, ready to be added after the WHERE clause. Empty if no sorting is given
+func (p *Plan) OrderSQL() string {
+	if !p.built {
+		p.BuildContext(p.buildContext())
+	}
+	return p.orderSQL
+}
+
+// Sorts returns the valid sorting fields of the plan, in order
+func (p *Plan) Sorts() []Sort {
+	if !p.built {
+		p.BuildContext(p.buildContext())
+	}
+	return p.sorts
+}
+
+// buildOrder parses the sorting specifications and builds the ORDER BY clause
+func (p *Plan) buildOrder(s *buildState) (string, []Sort) {
+	cfg := s.cfg
+	sorts := make([]Sort, 0)
+	items := make([]string, 0)
+	for i, spec := range p.orders {
+		ss := s.at(fmt.Sprintf("order[%d]", i))
+		parsed, err := parseSorts(spec)
+		if err != nil {
+			ss.report(&ConditionError{Value: spec, Reason: ReasonInvalidValue, Err: &InvalidValueError{Value: spec, Err: err}})
+			continue
+		}
+
+		for j, sort := range parsed {
+			fs := ss.at(fmt.Sprintf("[%d]", j))
+			report := func(reason string, err error) {
+				fs.report(&ConditionError{Field: sort.Field, Value: sort, Reason: reason, Err: err})
+			}
+			if sort.Field == "" || (sort.Nulls != "" && sort.Nulls != NullsFirst && sort.Nulls != NullsLast) {
+				report(ReasonInvalidValue, &InvalidValueError{Field: sort.Field, Value: sort, Err: errors.New("invalid sorting")})
+				continue
+			}
+			if !s.trusted && !cfg.isAllowedField(sort.Field) {
+				report(ReasonUnknownField, &UnknownFieldError{Field: sort.Field})
+				continue
+			}
+
+			sorts = append(sorts, sort)
+			items = append(items, orderItem(sort, cfg))
+		}
+	}
+
+	if len(items) == 0 {
+		return "", sorts
+	}
+	return "ORDER BY " + strings.Join(items, ", "), sorts
+}
+
+// orderItem renders the ORDER BY item of the sort
+func orderItem(sort Sort, cfg *Config) string {
+	column := processColumn(sort.Field, cfg)
+	direction := "ASC"
+	if sort.Desc {
+		direction = "DESC"
+	}
+	if sort.Nulls == "" {
+		return column + " " + direction
+	}
+	if no, ok := cfg.Dialect.(NullsOrderer); ok {
+		return no.OrderNulls(column, direction, sort.Nulls == NullsFirst)
+	}
+	return column + " " + direction + " NULLS " + strings.ToUpper(sort.Nulls)
+}
+
+// parseSorts converts the sorting specification to the list of Sort
+func parseSorts(spec interface{}) ([]Sort, error) {
+	switch v := spec.(type) {
+	case string:
+		sorts := make([]Sort, 0)
+		for _, token := range strings.Split(v, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				sorts = append(sorts, parseSortToken(token))
+			}
+		}
+		return sorts, nil
+	case []string:
+		sorts := make([]Sort, len(v))
+		for i, token := range v {
+			sorts[i] = parseSortToken(strings.TrimSpace(token))
+		}
+		return sorts, nil
+	case Sort:
+		return []Sort{v}, nil
+	case []Sort:
+		return v, nil
+	case []byte:
+		var decoded interface{}
+		if err := json.Unmarshal(v, &decoded); err != nil {
+			return nil, err
+		}
+		return parseSorts(decoded)
+	case json.RawMessage:
+		return parseSorts([]byte(v))
+	case map[string]interface{}:
+		sort := Sort{}
+		sort.Field, _ = v["field"].(string)
+		sort.Desc, _ = v["desc"].(bool)
+		sort.Nulls, _ = v["nulls"].(string)
+		return []Sort{sort}, nil
+	case []interface{}:
+		sorts := make([]Sort, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case string, map[string]interface{}:
+			default:
+				return nil, fmt.Errorf("unsupported sorting type %T", item)
+			}
+			parsed, err := parseSorts(item)
+			if err != nil {
+				return nil, err
+			}
+			sorts = append(sorts, parsed...)
+		}
+		return sorts, nil
+	}
+	return nil, fmt.Errorf("unsupported sorting type %T", spec)
+}
+
+// parseSortToken parses a single sorting token, e.g: "-paid_at:nulls_last"
+func parseSortToken(token string) Sort {
+	sort := Sort{}
+	if i := strings.LastIndex(token, ":"); i >= 0 {
+		switch token[i+1:] {
+		case "nulls_first":
+			sort.Nulls = NullsFirst
+		case "nulls_last":
+			sort.Nulls = NullsLast
+		default:
+			// invalid suffix, reported by the validation
+			sort.Nulls = token[i+1:]
+		}
+		token = token[:i]
+	}
+	if strings.HasPrefix(token, "-") {
+		sort.Desc = true
+		token = token[1:]
+	} else if strings.HasPrefix(token, "+") {
+		token = token[1:]
+	}
+	sort.Field = token
+	return sort
+}
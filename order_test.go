@@ -0,0 +1,92 @@
+package gowhere
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlan_OrderBy(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		spec interface{}
+		want string
+	}{
+		{
+			name: "string",
+			spec: "-created_at, name,+budget",
+			want: `ORDER BY "created_at" DESC, "name" ASC, "budget" ASC`,
+		},
+		{
+			name: "slice with aliases & table",
+			cfg:  Config{Table: "trips", ColumnAliases: map[string]string{"name": "full_name"}},
+			spec: []string{"name", "-id"},
+			want: `ORDER BY "trips"."full_name" ASC, "trips"."id" DESC`,
+		},
+		{
+			name: "json",
+			spec: []byte(`["-created_at", {"field": "paid_at", "nulls": "last"}, {"field": "name", "desc": true, "nulls": "first"}]`),
+			want: `ORDER BY "created_at" DESC, "paid_at" ASC NULLS LAST, "name" DESC NULLS FIRST`,
+		},
+		{
+			name: "decoded json in mysql",
+			cfg:  Config{Dialect: DialectMySQL},
+			spec: []interface{}{"-paid_at:nulls_last", map[string]interface{}{"field": "name", "nulls": "first"}},
+			want: "ORDER BY `paid_at` IS NULL ASC, `paid_at` DESC, `name` IS NULL DESC, `name` ASC",
+		},
+		{
+			name: "sorts",
+			spec: []Sort{{Field: "id", Desc: true}},
+			want: `ORDER BY "id" DESC`,
+		},
+		{
+			name: "empty",
+			spec: "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Strict = true
+			plan := WithConfig(tt.cfg).OrderBy(tt.spec).Build()
+			if plan.Error != nil {
+				t.Fatalf("unexpected error: %+v", plan.Error)
+			}
+			if got := plan.OrderSQL(); got != tt.want {
+				t.Errorf("Plan.OrderSQL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// allowed fields & invalid specs
+	plan := WithConfig(Config{AllowedFields: map[string][]string{"name": nil, "created_at": nil}}).
+		OrderBy("-created_at,password,name:nulls_middle").
+		OrderBy(json.RawMessage(`{"field": 1}`)).
+		OrderBy(123)
+	if got, want := plan.OrderSQL(), `ORDER BY "created_at" DESC`; got != want {
+		t.Errorf("Plan.OrderSQL() = %v, want %v", got, want)
+	}
+	if got, want := plan.Sorts(), []Sort{{Field: "created_at", Desc: true}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Plan.Sorts() = %v, want %v", got, want)
+	}
+	warnings := plan.Warnings()
+	wantWarnings := []struct {
+		path string
+		err  error
+	}{
+		{"order[0][1]", ErrUnknownField},
+		{"order[0][2]", ErrInvalidValue},
+		{"order[1][0]", ErrInvalidValue},
+		{"order[2]", ErrInvalidValue},
+	}
+	if len(warnings) != len(wantWarnings) {
+		t.Fatalf("Plan.Warnings() = %v, want %d warnings", warnings, len(wantWarnings))
+	}
+	for i, w := range wantWarnings {
+		if warnings[i].Path != w.path || !errors.Is(warnings[i], w.err) {
+			t.Errorf("warning #%d = %v, want %v at %v", i, warnings[i], w.err, w.path)
+		}
+	}
+}
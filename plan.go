@@ -1,27 +1,40 @@
 package gowhere
 
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
 // Plan contains information to build WHERE clause
 type Plan struct {
 	Error error
 
 	conditions *andConditions
+	scopes     *andConditions
 	config     *Config
+	ctx        context.Context
 	built      bool
+	inputs     int
+	warnings   Errors
+	orders     []interface{}
+	orderSQL   string
+	sorts      []Sort
+	cursor     *keyset
 	sql        string
+	stmtSQL    string
 	vars       []interface{}
+	havingSQL  string
+	havingVars []interface{}
 }
 
 // Where adds more condition(s) to the current Plan, using AND operator
 func (p *Plan) Where(cond interface{}, vars ...interface{}) *Plan {
-	condition, err := toCondition(cond, vars, false)
-	if err != nil {
-		if p.config.Strict {
-			p.Error = &InvalidCond{cond, vars}
-		}
-		return p
-	}
-
-	p.conditions.value = append(p.conditions.value, condition)
+	p.conditions.value = append(p.conditions.value, p.toInput(cond, vars, false))
 	p.built = false
 
 	return p
@@ -34,13 +47,7 @@ func (p *Plan) And(cond interface{}, vars ...interface{}) *Plan {
 
 // Or wraps all current conditions and ties with the new "cond" by OR operator
 func (p *Plan) Or(cond interface{}, vars ...interface{}) *Plan {
-	condition, err := toCondition(cond, vars, false)
-	if err != nil {
-		if p.config.Strict {
-			p.Error = &InvalidCond{cond, vars}
-		}
-		return p
-	}
+	condition := p.toInput(cond, vars, false)
 
 	p.conditions.naked = false
 	p.conditions = &andConditions{naked: true, value: []interface{}{&orConditions{value: []interface{}{p.conditions, condition}}}}
@@ -51,49 +58,169 @@ func (p *Plan) Or(cond interface{}, vars ...interface{}) *Plan {
 
 // Not works similar to Where but reverses the condition operator(s)
 func (p *Plan) Not(cond interface{}, vars ...interface{}) *Plan {
-	condition, err := toCondition(cond, vars, true)
+	p.conditions.value = append(p.conditions.value, p.toInput(cond, vars, true))
+	p.built = false
+
+	return p
+}
+
+// Scope adds mandatory condition(s) to the current Plan, which are always tied with the other conditions by AND operator.
+// Unlike Where, the scopes are kept outside of the conditions manipulated by Or & Not, and rendered first in the SQL clause.
+// It's meant for the conditions which must never be escaped by the user input, e.g: the tenant filter.
+// Problems of the scopes are always reported as errors, regardless of "Strict" mode.
+func (p *Plan) Scope(cond interface{}, vars ...interface{}) *Plan {
+	input := &inputConditions{index: len(p.scopes.value), scope: true}
+	condition, err := toCondition(cond, vars, false)
+	input.cond = condition
 	if err != nil {
-		if p.config.Strict {
-			p.Error = &InvalidCond{cond, vars}
-		}
-		return p
+		input.cond = &invalidConditions{cond: cond, vars: vars}
+		var errs Errors
+		input.build(&buildState{cfg: p.config, errs: &errs, fatalErrs: &errs})
+		p.Error = errs
 	}
 
-	p.conditions.value = append(p.conditions.value, condition)
+	p.scopes.value = append(p.scopes.value, input)
 	p.built = false
 
 	return p
 }
 
-// Build builds the SQL clause and vars with given conditions
+// toInput converts the given condition and records its position in the input.
+// An unsupported condition is kept to be reported on build, and fails the plan right away in "Strict" mode.
+func (p *Plan) toInput(cond interface{}, vars []interface{}, not bool) *inputConditions {
+	input := &inputConditions{index: p.inputs}
+	p.inputs++
+
+	condition, err := toCondition(cond, vars, not)
+	if err != nil {
+		input.cond = &invalidConditions{cond: cond, vars: vars}
+		if p.config.Strict {
+			var errs Errors
+			input.build(&buildState{cfg: p.config, errs: &errs})
+			p.Error = errs
+		}
+		return input
+	}
+	input.cond = condition
+
+	return input
+}
+
+// Build builds the SQL clause and vars with given conditions.
+// In "Strict" mode, all problems found in the conditions are reported at once as Errors.
+// Otherwise, the invalid conditions are skipped and reported as Warnings.
 func (p *Plan) Build() (rp *Plan) {
+	return p.BuildContext(context.Background())
+}
+
+// BuildContext works like Build, with the context given to the custom conditions and operators via Config.Context().
+// The values created by FromContext are also resolved from this context. The context is kept for Render & Match, and
+// for the rebuilds after the plan is changed, e.g: SQL() after SetTable.
+func (p *Plan) BuildContext(ctx context.Context) (rp *Plan) {
+	p.ctx = ctx
 	defer func() {
 		if err := recover(); err != nil {
-			switch e := err.(type) {
-			case *InvalidCond:
-				// better to double check the Strict config
-				if p.config.Strict {
-					p.Error = e
-				}
-			default:
-				// critical error
-				p.Error = err.(error)
+			// critical error
+			if e, ok := err.(error); ok {
+				p.Error = e
+			} else {
+				p.Error = fmt.Errorf("%v", err)
 			}
 			p.built = false
 			rp = p
 		}
 	}()
 
-	p.sql, p.vars = p.conditions.build(p.config)
+	s := p.newState(ctx, nil)
+	cfg := s.cfg
+	errs, fatalErrs := s.errs, s.fatalErrs
+	state := func(clause int) *buildState {
+		cs := *s
+		cs.clause = clause
+		return &cs
+	}
+
+	p.havingSQL, p.havingVars = "", []interface{}{}
+	if len(cfg.AggregateFields) > 0 {
+		p.sql, p.vars = sqlOf(p.buildTree(state(clauseWhere)))
+		p.havingSQL, p.havingVars = sqlOf(p.buildTree(state(clauseHaving)))
+	} else {
+		p.sql, p.vars = sqlOf(p.buildTree(state(clauseAll)))
+	}
+	p.orderSQL, p.sorts = p.buildOrder(state(clauseAll))
+	if p.cursor != nil {
+		p.buildCursor(state(clauseAll))
+	}
+
+	p.Error = s.buildError()
+	p.warnings = nil
+	if len(*errs) > 0 && !cfg.Strict {
+		p.warnings = *errs
+	}
+	if len(*fatalErrs) > 0 {
+		// fail closed, in case the caller doesn't check the error
+		p.sql, p.vars = "(1 = 0)", []interface{}{}
+		p.havingSQL, p.havingVars = "", []interface{}{}
+	}
+	// the literal "?" are kept marked for the statements, so they aren't bound
+	p.stmtSQL, p.sql = p.sql, unmarkLiterals(p.sql)
 	p.built = true
 
 	return p
 }
 
+// buildContext returns the context of the last build, or context.Background() if not built
+func (p *Plan) buildContext() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+// newState returns the state to build the plan with the context. The renderer is nil for Build, which renders the SQL
+// by Config.Renderer or SQLRenderer
+func (p *Plan) newState(ctx context.Context, r Renderer) *buildState {
+	cfg := *p.config
+	cfg.ctx = ctx
+	// all relative time values of the same build are resolved against the same time
+	now := cfg.now()
+	cfg.Now = func() time.Time { return now }
+
+	s := &buildState{cfg: &cfg, renderer: r, errs: &Errors{}, fatalErrs: &Errors{}}
+	if r == nil {
+		s.sql = true
+		s.renderer = SQLRenderer{}
+		if cfg.Renderer != nil {
+			s.renderer = cfg.Renderer
+		}
+	} else {
+		s.renderErrs = &Errors{}
+	}
+	return s
+}
+
+// buildError returns the problems of the build which are reported as the error: the fatal ones, and the others in
+// "Strict" mode. Nil if there are no such problems
+func (s *buildState) buildError() error {
+	errs := append(Errors{}, *s.fatalErrs...)
+	if s.cfg.Strict {
+		errs = append(errs, *s.errs...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// buildTree builds the scopes & the conditions of the plan with given state, tied by AND operator
+func (p *Plan) buildTree(s *buildState) interface{} {
+	return s.group(&Group{Root: true}, []interface{}{p.scopes.build(s), p.conditions.build(s)})
+}
+
 // SQL returns the built SQL clause
 func (p *Plan) SQL() string {
 	if !p.built {
-		p.Build()
+		p.BuildContext(p.buildContext())
 	}
 	return p.sql
 }
@@ -101,11 +228,58 @@ func (p *Plan) SQL() string {
 // Vars returns the list of vars for the built SQL clause
 func (p *Plan) Vars() []interface{} {
 	if !p.built {
-		p.Build()
+		p.BuildContext(p.buildContext())
 	}
 	return p.vars
 }
 
+// Fingerprint returns a stable hash of the built SQL clause. It only depends on the shape of the conditions,
+// not on the vars, so it can be used to group queries for metrics or as a cache key for prepared statements.
+func (p *Plan) Fingerprint() string {
+	// normalize whitespaces so formatting of raw conditions doesn't matter
+	sql := p.SQL()
+	if having := p.HavingSQL(); having != "" {
+		sql += " HAVING " + having
+	}
+	sum := sha256.Sum256([]byte(normalizeSpaces(sql)))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeSpaces collapses the whitespaces to a single space, except the ones in the quoted strings & identifiers
+func normalizeSpaces(sql string) string {
+	var b strings.Builder
+	var quote rune
+	space := false
+	for _, r := range strings.TrimSpace(sql) {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Warnings returns the problems of the conditions which were skipped in non-strict mode.
+// It has the same details as the errors in "Strict" mode, e.g: to log or notify about the ignored filters.
+func (p *Plan) Warnings() Errors {
+	if !p.built {
+		p.BuildContext(p.buildContext())
+	}
+	return p.warnings
+}
+
 // SetTable updates the `Table` config value
 func (p *Plan) SetTable(value string) *Plan {
 	p.config.Table = value
@@ -113,6 +287,13 @@ func (p *Plan) SetTable(value string) *Plan {
 	return p
 }
 
+// SetLocation updates the `Location` config value, e.g: to use the time zone of the current user
+func (p *Plan) SetLocation(loc *time.Location) *Plan {
+	p.config.Location = loc
+	p.built = false
+	return p
+}
+
 // SetColumnAliases updates the `ColumnAliases` config value
 func (p *Plan) SetColumnAliases(aliases map[string]string, mode ...rune) *Plan {
 	m := AppendMode
@@ -178,7 +359,12 @@ func toCondition(cond interface{}, vars []interface{}, not bool) (condition, err
 		return &orConditions{value: v, not: not}, nil
 	case string:
 		return &rawConditions{clause: c, vars: vars, not: not}, nil
+	case *ExistsCondition:
+		if not {
+			return &ExistsCondition{Subquery: c.Subquery, Not: !c.Not}, nil
+		}
+		return c, nil
 	default:
-		return nil, &InvalidCond{cond: cond, vars: vars}
+		return nil, &UnsupportedTypeError{Value: cond}
 	}
 }
@@ -0,0 +1,148 @@
+package gowhere
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Relation describes how the table of the plan relates to another table, so the conditions can traverse to the fields
+// of the related table, e.g: "author__name__icontains". Such conditions are rendered as correlated subqueries:
+// EXISTS (SELECT 1 FROM "authors" AS "author" WHERE "author"."id" = "trips"."author_id" AND ...).
+// The related table is aliased by the path of the relation, so a table can relate to itself, e.g: the parent of a trip.
+// Config.Table is required to refer to the columns of the current table
+type Relation struct {
+	// The related table
+	Table string
+	// The column of the current table. Default to "<relation name>_id" for one relation, or "id" for many relation
+	LocalKey string
+	// The column of the related table. Default to "id" for one relation. Required for many relation
+	ForeignKey string
+	// Whether there are many related rows, e.g: the comments of a post. Default to false, e.g: the author of a post.
+	// Negated conditions on many relation don't refer to the same related row, just like Django's exclude()
+	Many bool
+	// The relations of the related table, for multi-hop paths such as "author__company__name"
+	Relations map[string]Relation
+}
+
+// keys returns the local & foreign keys of the relation, with the default values applied
+func (r Relation) keys(name string) (string, string, error) {
+	local, foreign := r.LocalKey, r.ForeignKey
+	if r.Many {
+		if local == "" {
+			local = "id"
+		}
+		if foreign == "" {
+			return "", "", errors.New("missing foreign key of the many relation")
+		}
+	} else {
+		if local == "" {
+			local = name + "_id"
+		}
+		if foreign == "" {
+			foreign = "id"
+		}
+	}
+	return local, foreign, nil
+}
+
+// splitRelation returns the relation name & the rest of the key if the key traverses a relation
+func (c *Config) splitRelation(key string) (string, string, bool) {
+	if _, ok := c.CustomConditions[key]; ok {
+		return "", "", false
+	}
+	parts := strings.SplitN(key, c.Separator, 2)
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	if _, ok := c.Relations[parts[0]]; !ok {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// relationAllowedFields returns the allowed fields of the related table. The allowed fields may contain either the
+// relation name which allows all fields of the related table, or the full paths such as "author__name".
+func (c *Config) relationAllowedFields(name string) (map[string][]string, bool) {
+	if c.AllowedFields == nil {
+		return nil, true
+	}
+	if _, ok := c.AllowedFields[name]; ok {
+		return nil, true
+	}
+
+	prefix := name + c.Separator
+	fields := make(map[string][]string)
+	for field, ops := range c.AllowedFields {
+		if strings.HasPrefix(field, prefix) {
+			fields[strings.TrimPrefix(field, prefix)] = ops
+		}
+	}
+	return fields, len(fields) > 0
+}
+
+// buildRelation builds the conditions on the fields of the related table as correlated subqueries.
+// The "isnull" condition tests the existence of any related rows.
+func buildRelation(name string, conds map[string]interface{}, s *buildState) interface{} {
+	cfg := s.cfg
+	rel := cfg.Relations[name]
+	rs := s.at("." + s.prefix + name)
+	report := func(reason string, err error) {
+		rs.report(&ConditionError{Field: s.prefix + name, Value: conds, Reason: reason, Err: err})
+	}
+
+	allowed, ok := cfg.relationAllowedFields(name)
+	if !s.trusted && !ok {
+		report(ReasonUnknownField, &UnknownFieldError{Field: s.prefix + name})
+		return nil
+	}
+	local, foreign, err := rel.keys(name)
+	if err == nil && cfg.Table == "" {
+		err = errors.New("missing table of the plan, which is required by the relations")
+	}
+	if err != nil {
+		report(ReasonInvalidValue, &InvalidValueError{Field: s.prefix + name, Value: conds, Err: err})
+		return nil
+	}
+
+	alias := s.prefix + name
+	child := *cfg
+	child.Table = alias
+	child.Relations = rel.Relations
+	child.AllowedFields = allowed
+	child.ColumnAliases = map[string]string{}
+	child.CustomConditions = map[string]CustomConditionFn{}
+	child.AggregateFields = nil
+
+	join := fmt.Sprintf("%s = %s", processColumn(foreign, &child), processColumn(local, cfg))
+	exists := fmt.Sprintf("EXISTS (SELECT 1 FROM %s AS %s WHERE %s", cfg.Dialect.QuoteIdentifier(rel.Table),
+		cfg.Dialect.QuoteIdentifier(alias), join)
+
+	sqls := make([]string, 0, 2)
+	vars := make([]interface{}, 0)
+	rest := make(map[string]interface{}, len(conds))
+	for key, val := range conds {
+		if key != "isnull" {
+			rest[key] = val
+			continue
+		}
+		sql := exists + ")"
+		if null, ok := val.(bool); !ok || null {
+			sql = "NOT " + sql
+		}
+		sqls = append(sqls, sql)
+	}
+
+	if len(rest) > 0 {
+		cs := *s
+		cs.cfg = &child
+		cs.prefix = s.prefix + name + cfg.Separator
+		sql, _vars := sqlOf((&mapConditions{value: rest}).build(&cs))
+		if sql != "" {
+			sqls = append(sqls, exists+" AND "+sql+")")
+			vars = append(vars, _vars...)
+		}
+	}
+
+	return &SQLNode{SQL: strings.Join(sqls, " AND "), Vars: vars}
+}
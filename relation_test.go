@@ -0,0 +1,163 @@
+package gowhere
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRelation(t *testing.T) {
+	cfg := Config{
+		Table: "trips",
+		Relations: map[string]Relation{
+			"author": {
+				Table: "authors",
+				Relations: map[string]Relation{
+					"company": {Table: "companies", LocalKey: "employer_id"},
+				},
+			},
+			"comments": {Table: "comments", ForeignKey: "trip_id", Many: true},
+			"parent": {
+				Table:     "trips",
+				Relations: map[string]Relation{"parent": {Table: "trips"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		not      bool
+		cond     interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name: "one relation",
+			cond: map[string]interface{}{
+				"author__name__icontains": "bob",
+				"author__age__gte":        18,
+				"budget__gte":             1000,
+			},
+			wantSQL:  `(EXISTS (SELECT 1 FROM "authors" AS "author" WHERE "author"."id" = "trips"."author_id" AND ("author"."age" >= ? AND LOWER("author"."name") LIKE LOWER(?))) AND "trips"."budget" >= ?)`,
+			wantVars: []interface{}{18, "%bob%", 1000},
+		},
+		{
+			name: "multi-hop",
+			cond: map[string]interface{}{
+				"author__company__name": "Gopher Inc.",
+			},
+			wantSQL:  `(EXISTS (SELECT 1 FROM "authors" AS "author" WHERE "author"."id" = "trips"."author_id" AND (EXISTS (SELECT 1 FROM "companies" AS "author__company" WHERE "author__company"."id" = "author"."employer_id" AND ("author__company"."name" = ?)))))`,
+			wantVars: []interface{}{"Gopher Inc."},
+		},
+		{
+			name: "many relation",
+			cond: map[string]interface{}{
+				"comments__isnull":         false,
+				"comments__body__contains": "nice",
+				"comments__stars":          5,
+			},
+			wantSQL:  `(EXISTS (SELECT 1 FROM "comments" AS "comments" WHERE "comments"."trip_id" = "trips"."id") AND EXISTS (SELECT 1 FROM "comments" AS "comments" WHERE "comments"."trip_id" = "trips"."id" AND ("comments"."body" LIKE ? AND "comments"."stars" = ?)))`,
+			wantVars: []interface{}{"%nice%", 5},
+		},
+		{
+			name: "exclude on many relation",
+			not:  true,
+			cond: map[string]interface{}{
+				"comments__body__contains": "bad",
+				"comments__stars":          1,
+			},
+			wantSQL:  `NOT (EXISTS (SELECT 1 FROM "comments" AS "comments" WHERE "comments"."trip_id" = "trips"."id" AND ("comments"."body" LIKE ?)) AND EXISTS (SELECT 1 FROM "comments" AS "comments" WHERE "comments"."trip_id" = "trips"."id" AND ("comments"."stars" = ?)))`,
+			wantVars: []interface{}{"%bad%", 1},
+		},
+		{
+			name: "exclude on one relation",
+			not:  true,
+			cond: map[string]interface{}{
+				"author__isnull": true,
+				"author__name":   "bob",
+			},
+			wantSQL:  `NOT (NOT EXISTS (SELECT 1 FROM "authors" AS "author" WHERE "author"."id" = "trips"."author_id") AND EXISTS (SELECT 1 FROM "authors" AS "author" WHERE "author"."id" = "trips"."author_id" AND ("author"."name" = ?)))`,
+			wantVars: []interface{}{"bob"},
+		},
+		{
+			name: "allowed fields",
+			cfg: Config{
+				AllowedFields: map[string][]string{"author__name": {"exact"}, "comments": nil},
+			},
+			cond: map[string]interface{}{
+				"author__name":    "bob",
+				"comments__stars": 5,
+			},
+			wantSQL:  `(EXISTS (SELECT 1 FROM "authors" AS "author" WHERE "author"."id" = "trips"."author_id" AND ("author"."name" = ?)) AND EXISTS (SELECT 1 FROM "comments" AS "comments" WHERE "comments"."trip_id" = "trips"."id" AND ("comments"."stars" = ?)))`,
+			wantVars: []interface{}{"bob", 5},
+		},
+		{
+			name: "same table",
+			cond: map[string]interface{}{
+				"parent__parent__budget__gte": 1000,
+			},
+			wantSQL:  `(EXISTS (SELECT 1 FROM "trips" AS "parent" WHERE "parent"."id" = "trips"."parent_id" AND (EXISTS (SELECT 1 FROM "trips" AS "parent__parent" WHERE "parent__parent"."id" = "parent"."parent_id" AND ("parent__parent"."budget" >= ?)))))`,
+			wantVars: []interface{}{1000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			c.Strict = true
+			c.AllowedFields = tt.cfg.AllowedFields
+			plan := WithConfig(c)
+			if tt.not {
+				plan.Not(tt.cond)
+			} else {
+				plan.Where(tt.cond)
+			}
+			if plan.Build().Error != nil {
+				t.Fatalf("unexpected error: %+v", plan.Error)
+			}
+			if sql := plan.SQL(); sql != tt.wantSQL {
+				t.Errorf("sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if vars := plan.Vars(); !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+
+	c := cfg
+	c.Strict = true
+	c.AllowedFields = map[string][]string{"author__name": nil, "budget": nil}
+	plan := WithConfig(c).Where(map[string]interface{}{
+		"author__name__like":    "bob",
+		"author__age":           18,
+		"author__company__name": "Gopher Inc.",
+		"comments__stars":       5,
+	}).Build()
+	errs, _ := plan.Error.(Errors)
+	want := []struct {
+		path string
+		err  error
+	}{
+		{"[0].author__age", ErrUnknownField},
+		{"[0].author__company", ErrUnknownField},
+		{"[0].author__name__like", ErrUnknownOperator},
+		{"[0].comments", ErrUnknownField},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Error = %v, want %d errors", plan.Error, len(want))
+	}
+	for i, w := range want {
+		if errs[i].Path != w.path || !errors.Is(errs[i], w.err) {
+			t.Errorf("error #%d = %v, want %v at %v", i, errs[i], w.err, w.path)
+		}
+	}
+
+	// the columns of the current table can't be qualified without the table
+	c = cfg
+	c.Table = ""
+	c.Strict = true
+	plan = WithConfig(c).Where(map[string]interface{}{"author__name": "bob"}).Build()
+	if !errors.Is(plan.Error, ErrInvalidValue) {
+		t.Errorf("Error = %v, want %v", plan.Error, ErrInvalidValue)
+	}
+}
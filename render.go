@@ -0,0 +1,190 @@
+package gowhere
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Renderer renders the conditions tree to a backend, e.g: SQL or the query documents of other databases.
+// The rendered nodes are opaque to the conditions tree, nil means the condition is skipped.
+// SQLRenderer is the default implementation used by Build, see Config.Renderer
+type Renderer interface {
+	// Group ties the rendered items by AND or OR operator
+	Group(g *Group) (interface{}, error)
+	// Predicate renders a single condition of a map, e.g: {"name__icontains": "go"}
+	Predicate(p *Predicate) (interface{}, error)
+	// Raw renders a raw SQL condition, e.g: Where("id = ?", 1)
+	Raw(r *Raw) (interface{}, error)
+}
+
+// Group represents the list of conditions tied by AND or OR operator
+type Group struct {
+	// The location of the group in the input, see ConditionError
+	Path string
+	// Whether the items are tied by OR operator, otherwise AND
+	Or bool
+	// Whether the group is negated
+	Not bool
+	// Whether it's the top level group of the plan, which doesn't need the parentheses in SQL
+	Root bool
+	// The rendered items, without the skipped ones
+	Items []interface{}
+}
+
+// Predicate represents a single condition of a map, which is resolved & validated, e.g: the relative times are resolved
+type Predicate struct {
+	// The location of the condition in the input, see ConditionError
+	Path string
+	// The field of the condition
+	Field string
+	// The SQL column of the field, i.e: aliased, quoted & prefixed by the table
+	Column string
+	// The name of the operator in OperatorsList, e.g: "icontains". Empty for the unregistered operators
+	Operator string
+	// The operator
+	Op *Operator
+	// The value of the condition
+	Value interface{}
+	// The config of the build
+	Config *Config
+}
+
+// Raw represents a raw SQL condition, with the resolved vars
+type Raw struct {
+	// The location of the condition in the input, see ConditionError
+	Path   string
+	Clause string
+	Vars   []interface{}
+	Not    bool
+}
+
+// SQLNode is the node rendered by SQLRenderer. The renderer of Build must render this type
+type SQLNode struct {
+	SQL  string
+	Vars []interface{}
+}
+
+// SQLRenderer renders the conditions to the SQL clause. Embed it to customize parts of the SQL, e.g:
+//
+//	type myRenderer struct{ gowhere.SQLRenderer }
+//
+//	func (r myRenderer) Predicate(p *gowhere.Predicate) (interface{}, error) {
+//		if p.Operator == "icontains" {
+//			return &gowhere.SQLNode{SQL: p.Column + " ILIKE ?", Vars: []interface{}{"%" + gowhere.Utils.ToString(p.Value) + "%"}}, nil
+//		}
+//		return r.SQLRenderer.Predicate(p)
+//	}
+type SQLRenderer struct{}
+
+// Group joins the items by AND or OR, wrapped by parentheses except the top level group
+func (SQLRenderer) Group(g *Group) (interface{}, error) {
+	sqls := make([]string, 0, len(g.Items))
+	vars := make([]interface{}, 0)
+	for _, item := range g.Items {
+		node, ok := item.(*SQLNode)
+		if !ok {
+			return nil, fmt.Errorf("unexpected node type %T", item)
+		}
+		sqls = append(sqls, node.SQL)
+		vars = append(vars, node.Vars...)
+	}
+	if len(sqls) == 0 {
+		return nil, nil
+	}
+
+	operator := " AND "
+	if g.Or {
+		operator = " OR "
+	}
+	sql := strings.Join(sqls, operator)
+	if !g.Root || g.Not {
+		sql = "(" + sql + ")"
+		if g.Not {
+			sql = "NOT " + sql
+		}
+	}
+	return &SQLNode{SQL: sql, Vars: vars}, nil
+}
+
+// Predicate builds the condition by the operator. The column is converted to the time zone of the Location config
+// for the time values if the ConvertTimeZone config is enabled
+func (SQLRenderer) Predicate(p *Predicate) (interface{}, error) {
+	cfg, operator := p.Config, p.Op
+	timeValue := operator.TimeValue || cfg.isTimeField(p.Field)
+
+	var sql string
+	var vars []interface{}
+	if tc, ok := cfg.Dialect.(TimeZoneConverter); ok && timeValue && cfg.ConvertTimeZone && cfg.Location != nil &&
+		!(cfg.SargableDates && operator.SargableBuild != nil) {
+		// sargable ranges have the bounds with the offset of the location, so the column is kept unchanged
+		// the zone is the first var, as the column comes before the value in the templates
+		converted := *cfg
+		converted.naiveTimes = true
+		sql, vars = operator.Build(tc.ConvertTimeZone(p.Column), p.Value, &converted)
+		vars = append([]interface{}{sqlTimeZone(cfg)}, vars...)
+	} else {
+		sql, vars = operator.Build(p.Column, p.Value, cfg)
+	}
+
+	if sql == "" {
+		return nil, nil
+	}
+	return &SQLNode{SQL: sql, Vars: vars}, nil
+}
+
+// Raw wraps the clause by parentheses. Without vars, the "?" of the clause are operators, e.g: jsonb in PostgreSQL
+func (SQLRenderer) Raw(r *Raw) (interface{}, error) {
+	clause := r.Clause
+	if len(r.Vars) == 0 {
+		clause = markLiterals(clause)
+	}
+	sql := "(" + clause + ")"
+	if r.Not {
+		sql = "NOT " + sql
+	}
+	return &SQLNode{SQL: sql, Vars: r.Vars}, nil
+}
+
+// Render renders the scopes & the conditions of the plan, tied by AND operator, with the given renderer.
+// The tree is walked by the walker of Build, so the invalid conditions are skipped in non-strict mode, and the
+// same error as Plan.Error is returned otherwise. Relations, subqueries, expressions & aggregate fields are only
+// supported by Build, returning ErrNotRenderable. The values created by FromContext are resolved from the context of
+// the last BuildContext, a missing value is always an error. Returns nil if there are no conditions
+func (p *Plan) Render(r Renderer) (interface{}, error) {
+	return p.render(p.buildContext(), r)
+}
+
+// render walks the tree with the renderer. The problems of the conditions come first, then the first error of the renderer
+func (p *Plan) render(ctx context.Context, r Renderer) (interface{}, error) {
+	s := p.newState(ctx, r)
+	node := p.buildTree(s)
+	if err := s.buildError(); err != nil {
+		return nil, err
+	}
+	if len(*s.renderErrs) > 0 {
+		return nil, (*s.renderErrs)[0]
+	}
+	return node, nil
+}
+
+// rendererTarget returns the name of the target of the renderer, for the errors
+func rendererTarget(r Renderer) string {
+	if t, ok := r.(interface{ target() string }); ok {
+		return t.target()
+	}
+	return fmt.Sprintf("%T", r)
+}
+
+// operatorName returns the name of the operator in OperatorsList, or empty for the unregistered ones
+func operatorName(op *Operator) string {
+	if op == defaultOperator {
+		return "exact"
+	}
+	for name, o := range OperatorsList {
+		if o == op {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package gowhere
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ilikeRenderer renders the case insensitive operators by ILIKE
+type ilikeRenderer struct {
+	SQLRenderer
+}
+
+func (r ilikeRenderer) Predicate(p *Predicate) (interface{}, error) {
+	if p.Operator == "icontains" {
+		return &SQLNode{SQL: p.Column + " ILIKE ?", Vars: []interface{}{"%" + Utils.ToString(p.Value) + "%"}}, nil
+	}
+	return r.SQLRenderer.Predicate(p)
+}
+
+// textRenderer renders the conditions to a human readable text
+type textRenderer struct{}
+
+func (textRenderer) Group(g *Group) (interface{}, error) {
+	texts := make([]string, len(g.Items))
+	for i, item := range g.Items {
+		texts[i] = item.(string)
+	}
+	sep := " and "
+	if g.Or {
+		sep = " or "
+	}
+	text := "(" + strings.Join(texts, sep) + ")"
+	if g.Not {
+		text = "not " + text
+	}
+	return text, nil
+}
+
+func (textRenderer) Predicate(p *Predicate) (interface{}, error) {
+	if p.Operator == "between" {
+		return nil, errors.New("unsupported")
+	}
+	return fmt.Sprintf("%s %s %v", p.Field, p.Operator, p.Value), nil
+}
+
+func (textRenderer) Raw(r *Raw) (interface{}, error) {
+	return fmt.Sprintf("sql %q %v", r.Clause, r.Vars), nil
+}
+
+// wrongRenderer renders the nodes which are not *SQLNode
+type wrongRenderer struct {
+	SQLRenderer
+}
+
+func (wrongRenderer) Raw(r *Raw) (interface{}, error) {
+	return r.Clause, nil
+}
+
+func TestConfig_Renderer(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     *Plan
+		wantSQL  string
+		wantVars []interface{}
+		wantErr  bool
+	}{
+		{
+			name: "default",
+			plan: WithConfig(Config{Renderer: SQLRenderer{}}).Where(map[string]interface{}{"name__icontains": "go", "id": 1}).
+				Or("deleted = ?", false),
+			wantSQL:  `((("id" = ? AND LOWER("name") LIKE LOWER(?))) OR (deleted = ?))`,
+			wantVars: []interface{}{1, "%go%", false},
+		},
+		{
+			name: "custom predicate",
+			plan: WithConfig(Config{Renderer: ilikeRenderer{}}).Where(map[string]interface{}{"name__icontains": "go", "id": 1}).
+				Not(map[string]interface{}{"tag": "x"}),
+			wantSQL:  `("id" = ? AND "name" ILIKE ?) AND NOT ("tag" = ?)`,
+			wantVars: []interface{}{1, "%go%", "x"},
+		},
+		{
+			name:     "wrong node type",
+			plan:     WithConfig(Config{Renderer: wrongRenderer{}, Strict: true}).Where("deleted = ?", false),
+			wantSQL:  "",
+			wantVars: []interface{}{},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.plan.Build()
+			if (p.Error != nil) != tt.wantErr {
+				t.Fatalf("Build() error = %v, wantErr %v", p.Error, tt.wantErr)
+			}
+			if tt.wantErr && !reflect.DeepEqual(p.Error.(Errors)[0].Reason, ReasonNotRenderable) {
+				t.Errorf("Build() reason = %v, want %v", p.Error.(Errors)[0].Reason, ReasonNotRenderable)
+			}
+			if got := p.SQL(); got != tt.wantSQL {
+				t.Errorf("SQL() = %v, want %v", got, tt.wantSQL)
+			}
+			if got := p.Vars(); !reflect.DeepEqual(got, tt.wantVars) {
+				t.Errorf("Vars() = %v, want %v", got, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestPlan_Render(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    *Plan
+		want    interface{}
+		wantErr error
+	}{
+		{
+			name: "tree",
+			plan: Where(map[string]interface{}{"name__icontains": "go", "id": []int{1, 2}}).
+				Or("deleted = ?", false).Not(map[string]interface{}{"tag": "x"}).Scope(map[string]interface{}{"tenant_id": 7}),
+			want: "(((tenant_id exact 7)) and ((((id in [1 2] and name icontains go)) or sql \"deleted = ?\" [false]) and not (tag exact x)))",
+		},
+		{
+			name: "empty",
+			plan: Where(map[string]interface{}{}),
+			want: nil,
+		},
+		{
+			name:    "renderer error",
+			plan:    Where(map[string]interface{}{"budget__between": []interface{}{1, 2}}),
+			wantErr: &ConditionError{Path: "[0].budget__between", Reason: ReasonNotRenderable, Err: errors.New("unsupported")},
+		},
+		{
+			name: "relation",
+			plan: WithConfig(Config{Relations: map[string]Relation{"author": {Table: "authors"}}}).
+				Where(map[string]interface{}{"author__name": "x"}),
+			wantErr: &ConditionError{Path: "[0].author__name", Reason: ReasonNotRenderable,
+				Err: &NotRenderableError{Condition: "relation", Target: "gowhere.textRenderer"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.plan.Render(textRenderer{})
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Fatalf("Render() error = %#v, want %#v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Render() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
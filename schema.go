@@ -0,0 +1,280 @@
+package gowhere
+
+import (
+	"sort"
+	"strings"
+)
+
+// schemaDoc describes a built-in operator for the generated schemas
+type schemaDoc struct {
+	description string
+	// the schema of the value, given whether the field holds time values
+	value func(timeField bool) map[string]interface{}
+}
+
+var (
+	// the JSON types of the values which can be compared in SQL
+	scalarTypes = []interface{}{"string", "number", "boolean"}
+
+	schemaDocs = map[string]schemaDoc{
+		"exact":       {"equals the value", scalarSchema},
+		"iexact":      {"equals the value, case-insensitive", textSchema},
+		"notexact":    {"doesn't equal the value", scalarSchema},
+		"inotexact":   {"doesn't equal the value, case-insensitive", textSchema},
+		"gt":          {"is greater than the value", scalarSchema},
+		"gte":         {"is greater than or equal to the value", scalarSchema},
+		"lt":          {"is less than the value", scalarSchema},
+		"lte":         {"is less than or equal to the value", scalarSchema},
+		"startswith":  {"starts with the value", textSchema},
+		"istartswith": {"starts with the value, case-insensitive", textSchema},
+		"endswith":    {"ends with the value", textSchema},
+		"iendswith":   {"ends with the value, case-insensitive", textSchema},
+		"contains":    {"contains the value", textSchema},
+		"icontains":   {"contains the value, case-insensitive", textSchema},
+		"in":          {"equals one of the values", listSchema},
+		"notin":       {"doesn't equal any of the values", listSchema},
+		"date":        {"is on the date", dateSchema},
+		"year":        {"is in the year", yearSchema},
+		"between":     {"is between the two values, inclusive", rangeSchema},
+		"datebetween": {"is on a date between the two dates, inclusive", dateRangeSchema},
+		"isnull":      {"is null if the value is true, otherwise is not null", boolSchema},
+	}
+)
+
+// JSONSchema returns the JSON Schema of the filters accepted by the config of the plan, i.e: an object with every valid
+// key, such as "name__icontains", and the type of its value. The fields are the allowed fields, or the keys of the column
+// aliases, the aggregate fields & the time fields if all fields are allowed, which also allows the other keys.
+// The custom conditions accept any value, unless they're excluded by the allowed fields
+func (p *Plan) JSONSchema() map[string]interface{} {
+	schema := p.objectSchema()
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	return schema
+}
+
+// OpenAPIParameters returns the OpenAPI query parameters of the filters, one per valid key, e.g: "?budget__gte=100".
+// The arrays are comma separated, e.g: "?id__in=1,2,3"
+func (p *Plan) OpenAPIParameters() []map[string]interface{} {
+	props := p.objectSchema()["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	params := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		schema := make(map[string]interface{})
+		for k, v := range props[key].(map[string]interface{}) {
+			schema[k] = v
+		}
+		param := map[string]interface{}{"name": key, "in": "query", "required": false}
+		if desc, ok := schema["description"]; ok {
+			param["description"] = desc
+			delete(schema, "description")
+		}
+		if schema["type"] == "array" {
+			param["style"] = "form"
+			param["explode"] = false
+		}
+		param["schema"] = schema
+		params = append(params, param)
+	}
+	return params
+}
+
+// OpenAPIRequestBody returns the OpenAPI request body of the filters as a JSON object, see JSONSchema
+func (p *Plan) OpenAPIRequestBody() map[string]interface{} {
+	return map[string]interface{}{
+		"required": false,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": p.objectSchema()},
+		},
+	}
+}
+
+// objectSchema returns the schema of the object of the filters, without the $schema keyword which OpenAPI doesn't accept
+func (p *Plan) objectSchema() map[string]interface{} {
+	cfg := p.config
+	props := make(map[string]interface{})
+	patterns := make(map[string]interface{})
+
+	for _, field := range schemaFields(cfg) {
+		if _, ok := cfg.Relations[field]; ok && cfg.AllowedFields != nil {
+			// all fields of the related table are allowed
+			patterns["^"+field+cfg.Separator] = map[string]interface{}{"description": "conditions on the related " + field}
+			continue
+		}
+
+		timeField := cfg.isTimeField(field)
+		names := schemaOperators(cfg, field)
+		allowed := make(map[string]bool, len(names))
+		for _, name := range names {
+			allowed[name] = true
+		}
+		// the bare field implies the operator by the value, so only the allowed ones are described
+		var implied []interface{}
+		var descs []string
+		for _, im := range []struct {
+			name, desc string
+			schema     map[string]interface{}
+		}{
+			{"exact", "equals the value", scalarSchema(timeField)},
+			{"in", "equals one of the values of an array", listSchema(timeField)},
+			{"isnull", "is null", map[string]interface{}{"type": "null"}},
+		} {
+			if allowed[im.name] {
+				implied = append(implied, im.schema)
+				descs = append(descs, im.desc)
+			}
+		}
+		if len(implied) > 0 {
+			props[field] = map[string]interface{}{
+				"description": field + " " + strings.Join(descs, ", or "),
+				"anyOf":       implied,
+			}
+		}
+		for _, name := range names {
+			op := findOperatorByName(name)
+			schema := operatorSchema(op, timeField)
+			if desc := operatorDescription(op); desc != "" {
+				schema["description"] = field + " " + desc
+			}
+			props[field+cfg.Separator+name] = schema
+		}
+	}
+
+	// the custom conditions are always allowed, see Config.AllowedFields
+	for key := range cfg.CustomConditions {
+		props[key] = map[string]interface{}{"description": "custom condition"}
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": cfg.AllowedFields == nil,
+	}
+	if len(patterns) > 0 {
+		schema["patternProperties"] = patterns
+	}
+	return schema
+}
+
+// schemaFields returns the sorted fields to describe, see JSONSchema
+func schemaFields(cfg *Config) []string {
+	set := make(map[string]bool)
+	if cfg.AllowedFields != nil {
+		for field := range cfg.AllowedFields {
+			set[field] = true
+		}
+	} else {
+		for field := range cfg.ColumnAliases {
+			set[field] = true
+		}
+		for field := range cfg.AggregateFields {
+			set[field] = true
+		}
+		for _, field := range cfg.TimeFields {
+			set[field] = true
+		}
+	}
+	for key := range cfg.CustomConditions {
+		delete(set, key)
+	}
+
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// schemaOperators returns the sorted names of the operators allowed for the field
+func schemaOperators(cfg *Config, field string) []string {
+	names := cfg.AllowedFields[field]
+	if len(names) == 0 {
+		names = make([]string, 0, len(OperatorsList))
+		for name := range OperatorsList {
+			names = append(names, name)
+		}
+	}
+
+	valid := make([]string, 0, len(names))
+	for _, name := range names {
+		if findOperatorByName(name) != nil {
+			valid = append(valid, name)
+		}
+	}
+	sort.Strings(valid)
+	return valid
+}
+
+// operatorSchema returns the schema of the value of the operator, by its Schema or the built-in one
+func operatorSchema(op *Operator, timeField bool) map[string]interface{} {
+	schema := make(map[string]interface{})
+	if op.Schema != nil {
+		for k, v := range op.Schema {
+			schema[k] = v
+		}
+	} else if doc, ok := schemaDocs[operatorName(op)]; ok {
+		schema = doc.value(timeField)
+	}
+	return schema
+}
+
+// operatorDescription returns the description of the operator, by its Description or the built-in one
+func operatorDescription(op *Operator) string {
+	if op.Description != "" {
+		return op.Description
+	}
+	if doc, ok := schemaDocs[operatorName(op)]; ok {
+		return doc.description
+	}
+	return ""
+}
+
+// scalarSchema describes a single value, which is a date-time or a relative time such as "now-7d" for the time fields
+func scalarSchema(timeField bool) map[string]interface{} {
+	if timeField {
+		return map[string]interface{}{"type": "string"}
+	}
+	return map[string]interface{}{"type": scalarTypes}
+}
+
+// textSchema describes a text value
+func textSchema(bool) map[string]interface{} {
+	return map[string]interface{}{"type": "string"}
+}
+
+// listSchema describes a list of values
+func listSchema(timeField bool) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": scalarSchema(timeField)}
+}
+
+// rangeSchema describes the lower & upper bounds of a range
+func rangeSchema(timeField bool) map[string]interface{} {
+	schema := listSchema(timeField)
+	schema["minItems"] = 2
+	schema["maxItems"] = 2
+	return schema
+}
+
+// dateRangeSchema describes the first & last dates of a range
+func dateRangeSchema(bool) map[string]interface{} {
+	return rangeSchema(true)
+}
+
+// dateSchema describes a date, e.g: "2020-03-15", or a relative time such as "now-1d"
+func dateSchema(bool) map[string]interface{} {
+	return map[string]interface{}{"type": "string"}
+}
+
+// yearSchema describes a year, e.g: 2020
+func yearSchema(bool) map[string]interface{} {
+	return map[string]interface{}{"type": []interface{}{"integer", "string"}}
+}
+
+// boolSchema describes a boolean
+func boolSchema(bool) map[string]interface{} {
+	return map[string]interface{}{"type": "boolean"}
+}
@@ -0,0 +1,140 @@
+package gowhere
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPlan_JSONSchema(t *testing.T) {
+	plan := WithConfig(Config{
+		AllowedFields: map[string][]string{
+			"name":       {"icontains", "in"},
+			"budget":     {"between", "gte"},
+			"ended_at":   {"isnull"},
+			"created_at": {"date", "datebetween"},
+			"author":     nil,
+		},
+		TimeFields: []string{"created_at"},
+		Relations:  map[string]Relation{"author": {Table: "authors"}},
+		CustomConditions: map[string]CustomConditionFn{
+			"search": func(key string, val interface{}, cfg *Config) interface{} { return nil },
+		},
+	})
+	schema := plan.JSONSchema()
+	props := schema["properties"].(map[string]interface{})
+
+	tests := []struct {
+		key  string
+		want map[string]interface{}
+	}{
+		{
+			key:  "name__icontains",
+			want: map[string]interface{}{"type": "string", "description": "name contains the value, case-insensitive"},
+		},
+		{
+			key: "name__in",
+			want: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": scalarTypes},
+				"description": "name equals one of the values"},
+		},
+		{
+			key: "budget__between",
+			want: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": scalarTypes},
+				"minItems": 2, "maxItems": 2, "description": "budget is between the two values, inclusive"},
+		},
+		{
+			key:  "ended_at__isnull",
+			want: map[string]interface{}{"type": "boolean", "description": "ended_at is null if the value is true, otherwise is not null"},
+		},
+		{
+			key:  "created_at__date",
+			want: map[string]interface{}{"type": "string", "description": "created_at is on the date"},
+		},
+		{
+			key:  "search",
+			want: map[string]interface{}{"description": "custom condition"},
+		},
+		{
+			key:  "name__exact",
+			want: nil,
+		},
+		{
+			key:  "author",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, _ := props[tt.key].(map[string]interface{})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSONSchema() properties[%s] = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	if got := schema["additionalProperties"]; got != false {
+		t.Errorf("JSONSchema() additionalProperties = %v, want false", got)
+	}
+	if got := schema["patternProperties"]; !reflect.DeepEqual(got, map[string]interface{}{
+		"^author__": map[string]interface{}{"description": "conditions on the related author"},
+	}) {
+		t.Errorf("JSONSchema() patternProperties = %v", got)
+	}
+	if _, err := json.Marshal(schema); err != nil {
+		t.Errorf("JSONSchema() can't be marshaled: %v", err)
+	}
+
+	// the bare field is only described if one of its implied operators is allowed
+	props = WithConfig(Config{AllowedFields: map[string][]string{"name": {"icontains"}}}).JSONSchema()["properties"].(map[string]interface{})
+	if _, ok := props["name"]; ok || props["name__icontains"] == nil {
+		t.Errorf("JSONSchema() properties = %v, want only name__icontains", props)
+	}
+}
+
+func TestPlan_JSONSchema_AllFields(t *testing.T) {
+	OperatorsList["upper"] = &Operator{Template: "UPPER(%s) %s ?", Description: "in upper case equals the value",
+		Schema: map[string]interface{}{"type": "string"}}
+	defer delete(OperatorsList, "upper")
+
+	schema := WithConfig(Config{ColumnAliases: map[string]string{"name": "full_name"}}).JSONSchema()
+	props := schema["properties"].(map[string]interface{})
+	if len(props) != len(OperatorsList)+1 {
+		t.Errorf("JSONSchema() has %d properties, want %d", len(props), len(OperatorsList)+1)
+	}
+	if got, want := props["name__upper"], map[string]interface{}{"type": "string", "description": "name in upper case equals the value"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("JSONSchema() properties[name__upper] = %v, want %v", got, want)
+	}
+	if got := schema["additionalProperties"]; got != true {
+		t.Errorf("JSONSchema() additionalProperties = %v, want true", got)
+	}
+}
+
+func TestPlan_OpenAPIParameters(t *testing.T) {
+	plan := WithConfig(Config{AllowedFields: map[string][]string{"id": {"in"}}})
+	want := []map[string]interface{}{
+		{
+			"name": "id", "in": "query", "required": false,
+			"description": "id equals one of the values of an array",
+			"schema": map[string]interface{}{"anyOf": []interface{}{
+				map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": scalarTypes}},
+			}},
+		},
+		{
+			"name": "id__in", "in": "query", "required": false, "style": "form", "explode": false,
+			"description": "id equals one of the values",
+			"schema":      map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": scalarTypes}},
+		},
+	}
+	if got := plan.OpenAPIParameters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OpenAPIParameters() = %v, want %v", got, want)
+	}
+
+	body := plan.OpenAPIRequestBody()
+	schema := body["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+	if _, ok := schema["$schema"]; ok {
+		t.Errorf("OpenAPIRequestBody() schema has $schema keyword")
+	}
+	if got := len(schema["properties"].(map[string]interface{})); got != 2 {
+		t.Errorf("OpenAPIRequestBody() has %d properties, want 2", got)
+	}
+}
@@ -0,0 +1,98 @@
+package gowhere
+
+import "strconv"
+
+// Sqlizer is the interface of the SQL builders such as squirrel, e.g: squirrel.Select("*").From("trips").Where(plan)
+type Sqlizer interface {
+	ToSql() (string, []interface{}, error)
+}
+
+// havingSqlizer renders the HAVING clause of the plan
+type havingSqlizer struct {
+	plan *Plan
+}
+
+// whereSqlizer renders the WHERE clause of the plan, regardless of the HAVING clause
+type whereSqlizer struct {
+	plan *Plan
+}
+
+// ToSql returns the WHERE clause (without the keyword) & the vars, with the slices expanded to one placeholder per item,
+// so it can be used by the SQL builders which only accept the scalar args. Unlike SQL(), Plan.Error is returned.
+// The placeholders are always "?", as the builders usually rebind them, e.g: squirrel.Dollar.
+// An empty plan returns the tautology "(1=1)", as squirrel writes the WHERE keyword for every Sqlizer.
+// Like the execution helpers, returns ErrHavingQuery if the plan has conditions on the aggregate fields, which would
+// be lost. Use WhereClause & Having instead
+func (p *Plan) ToSql() (string, []interface{}, error) {
+	sql, vars, err := p.toSql(false)
+	return unmarkLiterals(sql), vars, err
+}
+
+// toSql returns the WHERE clause of ToSql, with the literal "?" still marked, see markLiterals
+func (p *Plan) toSql(withHaving bool) (string, []interface{}, error) {
+	if !p.built {
+		p.BuildContext(p.buildContext())
+	}
+	if p.Error == nil && p.havingSQL != "" && !withHaving {
+		return "", nil, ErrHavingQuery
+	}
+	return toSql(p.Error, p.stmtSQL, p.vars)
+}
+
+// WhereClause returns the Sqlizer of the WHERE clause only, to be used together with Having,
+// e.g: squirrel.Select("user_id").From("trips").Where(plan.WhereClause()).GroupBy("user_id").Having(plan.Having())
+func (p *Plan) WhereClause() Sqlizer {
+	return whereSqlizer{plan: p}
+}
+
+func (ws whereSqlizer) ToSql() (string, []interface{}, error) {
+	sql, vars, err := ws.plan.toSql(true)
+	return unmarkLiterals(sql), vars, err
+}
+
+// Having returns the Sqlizer of the HAVING clause, i.e: the conditions on the aggregate fields, see WhereClause
+func (p *Plan) Having() Sqlizer {
+	return havingSqlizer{plan: p}
+}
+
+func (hs havingSqlizer) ToSql() (string, []interface{}, error) {
+	p := hs.plan
+	if !p.built {
+		p.BuildContext(p.buildContext())
+	}
+	return toSql(p.Error, p.havingSQL, p.havingVars)
+}
+
+// toSql expands the args of the clause, or returns the tautology "(1=1)" for an empty clause
+func toSql(err error, sql string, vars []interface{}) (string, []interface{}, error) {
+	if err != nil {
+		return "", nil, err
+	}
+	if sql == "" {
+		return "(1=1)", []interface{}{}, nil
+	}
+	sql, vars = expandArgs(sql, vars, nil)
+	return sql, vars, nil
+}
+
+// ToNamed works like ToSql, but with the named placeholders for sqlx, i.e: ":gw1", ":gw2", ... and the map of the vars,
+// e.g: sqlx.NamedQuery(db, "SELECT * FROM trips WHERE "+sql, args). Merge the map if the query has its own named args
+func (p *Plan) ToNamed() (string, map[string]interface{}, error) {
+	sql, vars, err := p.toSql(false)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := make(map[string]interface{}, len(vars))
+	i := 0
+	sql = replacePlaceholders(sql, func() string {
+		if i >= len(vars) {
+			return "?"
+		}
+		i++
+		name := "gw" + strconv.Itoa(i)
+		args[name] = vars[i-1]
+		return ":" + name
+	})
+	return unmarkLiterals(sql), args, nil
+}
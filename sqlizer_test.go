@@ -0,0 +1,65 @@
+package gowhere
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlan_ToSql(t *testing.T) {
+	plan := WithConfig(Config{AggregateFields: map[string]string{"total": "SUM(budget)"}}).
+		Where(map[string]interface{}{"id__in": []int{1, 2}, "name": "x", "total__gt": 10})
+
+	// compile-time check of the Sqlizer interface
+	var sqlizer Sqlizer = plan
+	if _, _, err := sqlizer.ToSql(); err != ErrHavingQuery {
+		t.Errorf("Plan.ToSql() error = %v, want ErrHavingQuery", err)
+	}
+	if _, _, err := plan.ToNamed(); err != ErrHavingQuery {
+		t.Errorf("Plan.ToNamed() error = %v, want ErrHavingQuery", err)
+	}
+
+	sql, vars, err := plan.WhereClause().ToSql()
+	if err != nil {
+		t.Fatalf("Plan.WhereClause().ToSql() error = %v", err)
+	}
+	if want := `("id" IN (?, ?) AND "name" = ?)`; sql != want {
+		t.Errorf("Plan.WhereClause().ToSql() sql = %v, want %v", sql, want)
+	}
+	if want := []interface{}{1, 2, "x"}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("Plan.WhereClause().ToSql() vars = %v, want %v", vars, want)
+	}
+
+	sql, vars, err = plan.Having().ToSql()
+	if err != nil || sql != `(SUM(budget) > ?)` || !reflect.DeepEqual(vars, []interface{}{10}) {
+		t.Errorf("Plan.Having().ToSql() = %v, %v, %v", sql, vars, err)
+	}
+
+	named, args, err := Where(map[string]interface{}{"id__in": []int{1, 2}, "name": "x"}).ToNamed()
+	if err != nil {
+		t.Fatalf("Plan.ToNamed() error = %v", err)
+	}
+	if want := `("id" IN (:gw1, :gw2) AND "name" = :gw3)`; named != want {
+		t.Errorf("Plan.ToNamed() sql = %v, want %v", named, want)
+	}
+	if want := map[string]interface{}{"gw1": 1, "gw2": 2, "gw3": "x"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("Plan.ToNamed() args = %v, want %v", args, want)
+	}
+
+	// squirrel doesn't skip an empty Sqlizer
+	sql, vars, err = Where(map[string]interface{}{}).ToSql()
+	if err != nil || sql != "(1=1)" || len(vars) != 0 {
+		t.Errorf("Plan.ToSql() = %v, %v, %v, want (1=1)", sql, vars, err)
+	}
+	if sql, _, _ = Where(map[string]interface{}{"id": 1}).Having().ToSql(); sql != "(1=1)" {
+		t.Errorf("Plan.Having().ToSql() = %v, want (1=1)", sql)
+	}
+
+	invalid := WithConfig(Config{Strict: true}).Where(map[string]interface{}{"id__unknown": 1})
+	if _, _, err := invalid.ToSql(); !errors.Is(err, ErrUnknownOperator) {
+		t.Errorf("Plan.ToSql() error = %v, want ErrUnknownOperator", err)
+	}
+	if _, _, err := invalid.ToNamed(); !errors.Is(err, ErrUnknownOperator) {
+		t.Errorf("Plan.ToNamed() error = %v, want ErrUnknownOperator", err)
+	}
+}
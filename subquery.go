@@ -0,0 +1,142 @@
+package gowhere
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Subquery describes a subquery, which can be the value of in/notin operators or given to Exists/NotExists.
+// A *Plan can also be used directly as the value, which equals to Subquery{Where: plan}
+type Subquery struct {
+	// The table to select from. Default to the Table config of the Where plan
+	Table string
+	// The column to select for in/notin operators. Default to "id", just like Django selects the primary key.
+	// Exists/NotExists always select 1
+	Column string
+	// The conditions of the subquery. Optional
+	Where *Plan
+}
+
+// ExistsCondition represents the condition "EXISTS (SELECT 1 FROM table WHERE ...)"
+type ExistsCondition struct {
+	Subquery Subquery
+	Not      bool
+}
+
+// Exists returns the condition which tests the existence of any rows of the subquery, either a *Plan or a Subquery
+func Exists(query interface{}) *ExistsCondition {
+	sub, _ := toSubquery(query)
+	return &ExistsCondition{Subquery: sub}
+}
+
+// NotExists is the opposite of Exists
+func NotExists(query interface{}) *ExistsCondition {
+	sub, _ := toSubquery(query)
+	return &ExistsCondition{Subquery: sub, Not: true}
+}
+
+func (ec *ExistsCondition) build(s *buildState) interface{} {
+	if s.clause == clauseHaving {
+		return nil
+	}
+	if !s.sql {
+		return s.unsupported("subquery")
+	}
+	sql, vars, err := ec.Subquery.build(s, "1")
+	if err != nil {
+		operator := "exists"
+		if ec.Not {
+			operator = "notexists"
+		}
+		s.report(&ConditionError{
+			Operator: operator,
+			Value:    ec.Subquery,
+			Reason:   ReasonInvalidValue,
+			Err:      &InvalidValueError{Operator: operator, Value: ec.Subquery, Err: err},
+		})
+		return nil
+	}
+
+	sql = "EXISTS (" + sql + ")"
+	if ec.Not {
+		sql = "NOT " + sql
+	}
+	return &SQLNode{SQL: sql, Vars: vars}
+}
+
+// build returns the SELECT statement of the subquery. The nested plan is built within the current state,
+// i.e: using the same dialect, context and reporting the problems with the path of the current condition.
+func (sq Subquery) build(s *buildState, selection string) (string, []interface{}, error) {
+	plan := sq.Where
+	if plan == nil {
+		plan = WithConfig(Config{})
+	}
+
+	cfg := *plan.config
+	cfg.Dialect = s.cfg.Dialect
+	cfg.ctx = s.cfg.ctx
+	cfg.Now = s.cfg.Now
+	if sq.Table != "" {
+		cfg.Table = sq.Table
+	}
+	if cfg.Table == "" {
+		return "", nil, errors.New("missing table of the subquery")
+	}
+	if selection == "" {
+		column := sq.Column
+		if column == "" {
+			column = "id"
+		}
+		selection = processColumn(column, &cfg)
+	}
+
+	ns := *s
+	ns.cfg = &cfg
+	ns.root = s.path
+	ns.clause = clauseAll
+	sql, vars := sqlOf(plan.buildTree(&ns))
+
+	stmt := fmt.Sprintf("SELECT %s FROM %s", selection, cfg.Dialect.QuoteIdentifier(cfg.Table))
+	if sql != "" {
+		stmt += " WHERE " + sql
+	}
+	return stmt, vars, nil
+}
+
+// toSubquery converts the value to a subquery, if possible
+func toSubquery(value interface{}) (Subquery, bool) {
+	switch v := value.(type) {
+	case Subquery:
+		return v, true
+	case *Subquery:
+		if v != nil {
+			return *v, true
+		}
+	case *Plan:
+		if v != nil {
+			return Subquery{Where: v}, true
+		}
+	}
+	return Subquery{}, false
+}
+
+// buildSubquery builds the condition of an operator with the subquery as value.
+// Only operators which wrap the value by parentheses in the template are supported, i.e: in & notin
+func (o *Operator) buildSubquery(field string, sub Subquery, s *buildState) (string, []interface{}, error) {
+	if o.CustomBuild != nil || !strings.Contains(o.Template, "(?)") {
+		return "", nil, errors.New("subquery is only supported by in/notin operators")
+	}
+
+	sql, vars, err := sub.build(s, "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	operator := o.Operator
+	if operator == "" {
+		operator = "="
+	}
+	template := strings.Replace(o.Template, "(?)", "("+escapeFormat(sql)+")", 1)
+	return fmt.Sprintf(template, field, operator), vars, nil
+}
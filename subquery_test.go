@@ -0,0 +1,106 @@
+package gowhere
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSubquery(t *testing.T) {
+	users := func() *Plan {
+		return WithConfig(Config{Table: "users"}).Where(map[string]interface{}{"name__contains": "Go", "active": true})
+	}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		cond     interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name: "plan as value of in",
+			cond: map[string]interface{}{
+				"budget__gte":  1000,
+				"user_id__in":  users(),
+				"zone__notin":  []string{"A", "B"},
+				"name__iexact": "trip",
+			},
+			wantSQL:  `("budget" >= ? AND LOWER("name") = LOWER(?) AND "user_id" IN (SELECT "users"."id" FROM "users" WHERE ("users"."active" = ? AND "users"."name" LIKE ?)) AND "zone" NOT IN (?))`,
+			wantVars: []interface{}{1000, "trip", true, "%Go%", []string{"A", "B"}},
+		},
+		{
+			name: "subquery without operator",
+			cfg:  Config{Dialect: DialectMySQL},
+			cond: map[string]interface{}{
+				"user_id": Subquery{Table: "members", Column: "user_id", Where: users().Scope("deleted_at IS NULL")},
+			},
+			wantSQL:  "(`user_id` IN (SELECT `members`.`user_id` FROM `members` WHERE (deleted_at IS NULL) AND (`members`.`active` = ? AND `members`.`name` LIKE ?)))",
+			wantVars: []interface{}{true, "%Go%"},
+		},
+		{
+			name: "notin subquery",
+			cond: map[string]interface{}{
+				"user_id__notin": &Subquery{Table: "banned_users", Column: "user_id"},
+			},
+			wantSQL:  `("user_id" NOT IN (SELECT "banned_users"."user_id" FROM "banned_users"))`,
+			wantVars: []interface{}{},
+		},
+		{
+			name: "exists",
+			cond: []interface{}{
+				map[string]interface{}{"public": true},
+				Exists(WithConfig(Config{Table: "shares"}).Where("shares.trip_id = trips.id AND shares.user_id = ?", 7)),
+			},
+			wantSQL:  `(("public" = ?) OR EXISTS (SELECT 1 FROM "shares" WHERE (shares.trip_id = trips.id AND shares.user_id = ?)))`,
+			wantVars: []interface{}{true, 7},
+		},
+		{
+			name:     "not exists",
+			cond:     NotExists(Subquery{Table: "reports"}),
+			wantSQL:  `NOT EXISTS (SELECT 1 FROM "reports")`,
+			wantVars: []interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Strict = true
+			plan := WithConfig(tt.cfg).Where(tt.cond).Build()
+			if plan.Error != nil {
+				t.Fatalf("unexpected error: %+v", plan.Error)
+			}
+			if sql := plan.SQL(); sql != tt.wantSQL {
+				t.Errorf("sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if vars := plan.Vars(); !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+
+	// reversed by Not
+	plan := WithConfig(Config{}).Not(Exists(Subquery{Table: "reports"}))
+	if sql := plan.SQL(); sql != `NOT EXISTS (SELECT 1 FROM "reports")` {
+		t.Errorf("sql = %v", sql)
+	}
+
+	// problems of the nested plan are reported with the path of the outer condition
+	plan = WithConfig(Config{Strict: true}).Where(map[string]interface{}{
+		"user_id__in":   WithConfig(Config{Table: "users"}).Where(map[string]interface{}{"name__like": "Go"}),
+		"owner_id__gte": Subquery{Table: "users"},
+		"member_id__in": WithConfig(Config{}),
+	}).Build()
+	errs, _ := plan.Error.(Errors)
+	wantPaths := []string{"[0].member_id__in", "[0].owner_id__gte", "[0].user_id__in[0].name__like"}
+	if len(errs) != len(wantPaths) {
+		t.Fatalf("Error = %v, want %d errors", plan.Error, len(wantPaths))
+	}
+	for i, path := range wantPaths {
+		if errs[i].Path != path {
+			t.Errorf("error #%d = %v, want path %v", i, errs[i], path)
+		}
+	}
+	if !errors.Is(errs[0], ErrInvalidValue) || !errors.Is(errs[1], ErrInvalidValue) || !errors.Is(errs[2], ErrUnknownOperator) {
+		t.Errorf("Error = %v", plan.Error)
+	}
+}
@@ -3,6 +3,8 @@ package gowhere
 import (
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -96,3 +98,133 @@ func (u utilsCollection) ToDate(val interface{}) string {
 		return u.ToString(val)
 	}
 }
+
+// ParseRelativeTime parses the relative time expression against the given time, such as:
+// "now", "today", "yesterday", "tomorrow", "startOf:month", "endOf:week", "now-7d", "today+1w", "-1h" (i.e "now-1h").
+// Units of the offsets: "s" second, "m" minute, "h" hour, "d" day, "w" week, "M" month and "y" year.
+// Returns false if the expression is not a relative time.
+func (u utilsCollection) ParseRelativeTime(expr string, now time.Time) (time.Time, bool) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return time.Time{}, false
+	}
+
+	t := now
+	rest := expr
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch {
+	case strings.HasPrefix(rest, "now"):
+		rest = rest[3:]
+	case strings.HasPrefix(rest, "today"):
+		t, rest = today, rest[5:]
+	case strings.HasPrefix(rest, "yesterday"):
+		t, rest = today.AddDate(0, 0, -1), rest[9:]
+	case strings.HasPrefix(rest, "tomorrow"):
+		t, rest = today.AddDate(0, 0, 1), rest[8:]
+	case strings.HasPrefix(rest, "startOf:"), strings.HasPrefix(rest, "endOf:"):
+		end := strings.HasPrefix(rest, "endOf:")
+		rest = rest[strings.IndexRune(rest, ':')+1:]
+		n := strings.IndexAny(rest, "+-")
+		if n < 0 {
+			n = len(rest)
+		}
+		start, next, ok := periodOf(now, rest[:n])
+		if !ok {
+			return time.Time{}, false
+		}
+		t, rest = start, rest[n:]
+		if end {
+			t = next.Add(-time.Nanosecond)
+		}
+	case rest[0] != '+' && rest[0] != '-':
+		return time.Time{}, false
+	}
+
+	for rest != "" {
+		// each offset is in form of: sign, number, unit
+		sign := 1
+		switch rest[0] {
+		case '+':
+		case '-':
+			sign = -1
+		default:
+			return time.Time{}, false
+		}
+		i := 1
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 1 || i == len(rest) {
+			return time.Time{}, false
+		}
+		n, err := strconv.Atoi(rest[1:i])
+		if err != nil {
+			return time.Time{}, false
+		}
+		n *= sign
+
+		switch rest[i] {
+		case 's':
+			t = t.Add(time.Duration(n) * time.Second)
+		case 'm':
+			t = t.Add(time.Duration(n) * time.Minute)
+		case 'h':
+			t = t.Add(time.Duration(n) * time.Hour)
+		case 'd':
+			t = t.AddDate(0, 0, n)
+		case 'w':
+			t = t.AddDate(0, 0, 7*n)
+		case 'M':
+			t = t.AddDate(0, n, 0)
+		case 'y':
+			t = t.AddDate(n, 0, 0)
+		default:
+			return time.Time{}, false
+		}
+		rest = rest[i+1:]
+	}
+
+	return t, true
+}
+
+// ParseRelativeRange parses the range of two relative time expressions separated by "..", e.g: "-1w..now".
+// Returns false if any side of the range is not a relative time.
+func (u utilsCollection) ParseRelativeRange(expr string, now time.Time) (time.Time, time.Time, bool) {
+	parts := strings.Split(expr, "..")
+	if len(parts) != 2 {
+		return time.Time{}, time.Time{}, false
+	}
+	from, ok := u.ParseRelativeTime(parts[0], now)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	to, ok := u.ParseRelativeTime(parts[1], now)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// periodOf returns the start of the period containing the given time, and the start of the next period.
+// Weeks start on Monday.
+func periodOf(t time.Time, unit string) (time.Time, time.Time, bool) {
+	y, m, d := t.Date()
+	loc := t.Location()
+	switch unit {
+	case "day":
+		start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 1), true
+	case "week":
+		offset := (int(t.Weekday()) + 6) % 7
+		start := time.Date(y, m, d-offset, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 7), true
+	case "month":
+		start := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0), true
+	case "year":
+		start := time.Date(y, time.January, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(1, 0, 0), true
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -235,3 +235,50 @@ func TestToDate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRelativeTime(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, time.March, 13, 15, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		input string
+		want  time.Time
+		ok    bool
+	}{
+		{name: "now", input: "now", want: now, ok: true},
+		{name: "today", input: "today", want: time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), ok: true},
+		{name: "yesterday", input: "yesterday", want: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC), ok: true},
+		{name: "tomorrow", input: "tomorrow", want: time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC), ok: true},
+		{name: "offset", input: "now-7d", want: time.Date(2024, 3, 6, 15, 30, 45, 0, time.UTC), ok: true},
+		{name: "multiple offsets", input: "today+1M-2h", want: time.Date(2024, 4, 12, 22, 0, 0, 0, time.UTC), ok: true},
+		{name: "offset without base", input: "-1w", want: time.Date(2024, 3, 6, 15, 30, 45, 0, time.UTC), ok: true},
+		{name: "minutes & seconds", input: "now+5m-45s", want: time.Date(2024, 3, 13, 15, 35, 0, 0, time.UTC), ok: true},
+		{name: "start of week", input: "startOf:week", want: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), ok: true},
+		{name: "start of month", input: "startOf:month", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{name: "start of last year", input: "startOf:year-1y", want: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{name: "end of day", input: "endOf:day", want: time.Date(2024, 3, 13, 23, 59, 59, 999999999, time.UTC), ok: true},
+		{name: "absolute date", input: "2024-03-01"},
+		{name: "unknown unit", input: "now-7x"},
+		{name: "unknown period", input: "startOf:decade"},
+		{name: "missing number", input: "now-d"},
+		{name: "text", input: "nowhere"},
+		{name: "empty", input: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Utils.ParseRelativeTime(tt.input, now)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("Utils.ParseRelativeTime() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	from, to, ok := Utils.ParseRelativeRange("-1w..now", now)
+	if !ok || !from.Equal(now.AddDate(0, 0, -7)) || !to.Equal(now) {
+		t.Errorf("Utils.ParseRelativeRange() = %v, %v, %v", from, to, ok)
+	}
+	if _, _, ok := Utils.ParseRelativeRange("2024-03-01..now", now); ok {
+		t.Errorf("Utils.ParseRelativeRange() should reject absolute dates")
+	}
+}
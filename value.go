@@ -0,0 +1,200 @@
+package gowhere
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// inlineValue represents a value which is rendered inline in the SQL clause, instead of a "?" placeholder
+type inlineValue interface {
+	inline(cfg *Config) (string, []interface{})
+}
+
+// ColumnValue represents a reference to another column as the value of a condition, e.g: ended_at > started_at
+type ColumnValue struct {
+	Name string
+}
+
+// Col returns the reference to the given column, which is quoted and aliased just like the field of the condition.
+// For the input from frontend apps, the JSON-safe form {"$col": "started_at"} is also accepted.
+func Col(name string) ColumnValue {
+	return ColumnValue{Name: name}
+}
+
+func (cv ColumnValue) inline(cfg *Config) (string, []interface{}) {
+	return processColumn(cv.Name, cfg), []interface{}{}
+}
+
+// ExprValue represents a SQL expression with its args as the value of a condition, e.g: NOW() - INTERVAL '7 days'
+type ExprValue struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Expr returns a SQL expression which is rendered inline instead of the "?" placeholder, with its args added to the vars.
+// The expression is wrapped by parentheses and not modified by ModValue of the operator.
+// Note: it's only available from Go code, the input from frontend apps is never decoded as an expression.
+func Expr(sql string, args ...interface{}) ExprValue {
+	return ExprValue{SQL: sql, Args: args}
+}
+
+func (ev ExprValue) inline(cfg *Config) (string, []interface{}) {
+	vars := make([]interface{}, len(ev.Args))
+	for i, arg := range ev.Args {
+		vars[i] = Utils.ToSQLVar(arg)
+	}
+	return "(" + ev.SQL + ")", vars
+}
+
+// resolveValue replaces the placeholders in the value, including the items of a []interface{}:
+// the context values are resolved from the context of the build, and {"$col": "name"} maps are converted to ColumnValue
+func resolveValue(val interface{}, cfg *Config) (interface{}, error) {
+	switch v := val.(type) {
+	case ContextValue:
+		res := cfg.Context().Value(v.Key)
+		if res == nil {
+			return nil, fmt.Errorf("missing context value of key %v", v.Key)
+		}
+		return res, nil
+	case map[string]interface{}:
+		if name, ok := v["$col"].(string); ok && len(v) == 1 {
+			return Col(name), nil
+		}
+	case []interface{}:
+		var resolved []interface{}
+		for i, item := range v {
+			res, err := resolveValue(item, cfg)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := item.(ContextValue); !ok {
+				if _, ok := res.(ColumnValue); !ok {
+					continue
+				}
+			}
+			if resolved == nil {
+				resolved = make([]interface{}, len(v))
+				copy(resolved, v)
+			}
+			resolved[i] = res
+		}
+		if resolved != nil {
+			return resolved, nil
+		}
+	}
+	return val, nil
+}
+
+// resolveRelativeTime replaces the relative time expressions in the value, including the items of a []string or
+// []interface{}. If ranged, a single string such as "-1w..now" is resolved to the range of two times.
+func resolveRelativeTime(val interface{}, now time.Time, ranged bool) interface{} {
+	switch v := val.(type) {
+	case string:
+		if ranged {
+			if from, to, ok := Utils.ParseRelativeRange(v, now); ok {
+				return []interface{}{from, to}
+			}
+		}
+		if t, ok := Utils.ParseRelativeTime(v, now); ok {
+			return t
+		}
+	case []string:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = resolveRelativeTime(item, now, false)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = resolveRelativeTime(item, now, false)
+		}
+		return res
+	}
+	return val
+}
+
+// convertTimes converts the times in the value, including the items of a slice, to the given location.
+// Strings in RFC3339 format are parsed as times with their offsets.
+func convertTimes(val interface{}, loc *time.Location) interface{} {
+	switch v := val.(type) {
+	case time.Time:
+		if loc != nil {
+			return v.In(loc)
+		}
+	case *time.Time:
+		if v != nil {
+			return convertTimes(*v, loc)
+		}
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return convertTimes(t, loc)
+		}
+	case []string:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = convertTimes(item, loc)
+		}
+		return res
+	case []time.Time:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = convertTimes(item, loc)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = convertTimes(item, loc)
+		}
+		return res
+	}
+	return val
+}
+
+// checkInline validates the inline values, including the items of a slice which are only allowed as the bounds of a
+// range, since the lists are bound as a single var.
+// Returns UnknownFieldError if the referenced column is not allowed, or the reason why the value is invalid.
+func checkInline(val interface{}, cfg *Config, trusted, ranged bool) error {
+	switch v := val.(type) {
+	case ColumnValue:
+		if !trusted && !cfg.isAllowedField(v.Name) {
+			return &UnknownFieldError{Field: v.Name}
+		}
+	case ExprValue:
+		if n := strings.Count(v.SQL, "?"); n != len(v.Args) {
+			return fmt.Errorf("expression %q has %d placeholder(s) but %d arg(s)", v.SQL, n, len(v.Args))
+		}
+	default:
+		rv := reflect.ValueOf(val)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil
+		}
+		for i := 0; i < rv.Len(); i++ {
+			item := rv.Index(i).Interface()
+			if _, ok := item.(inlineValue); ok && !ranged {
+				return fmt.Errorf("column reference or expression %+v is not supported in a list", item)
+			}
+			if err := checkInline(item, cfg, trusted, ranged); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindVar returns the placeholder & the var of a value. Inline values are rendered as is, otherwise the value is
+// converted by the given func
+func bindVar(val interface{}, cfg *Config, convert func(interface{}) interface{}) (string, []interface{}) {
+	if iv, ok := val.(inlineValue); ok {
+		return iv.inline(cfg)
+	}
+	return "?", []interface{}{convert(val)}
+}
+
+// escapeFormat escapes the "%" characters to use the string in a format template
+func escapeFormat(s string) string {
+	return strings.Replace(s, "%", "%%", -1)
+}
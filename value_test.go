@@ -0,0 +1,138 @@
+package gowhere
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestColumnValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		cond     interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name: "column reference",
+			cfg:  Config{Table: "trips", ColumnAliases: map[string]string{"budget": "max_budget"}},
+			cond: map[string]interface{}{
+				"ended_at__gt": Col("started_at"),
+				"spent__lte":   Col("budget"),
+				"name":         "Go",
+			},
+			wantSQL:  `("trips"."ended_at" > "trips"."started_at" AND "trips"."name" = ? AND "trips"."spent" <= "trips"."max_budget")`,
+			wantVars: []interface{}{"Go"},
+		},
+		{
+			name: "json-safe column reference",
+			cfg:  Config{Dialect: DialectMySQL},
+			cond: map[string]interface{}{
+				"ended_at__gt":     map[string]interface{}{"$col": "started_at"},
+				"name__iexact":     map[string]interface{}{"$col": "title"},
+				"name__contains":   Col("title"),
+				"paid_at__between": []interface{}{map[string]interface{}{"$col": "started_at"}, "2019-04-19"},
+			},
+			wantSQL:  "(`ended_at` > `started_at` AND `name` LIKE `title` AND LOWER(`name`) = LOWER(`title`) AND `paid_at` BETWEEN `started_at` AND ?)",
+			wantVars: []interface{}{"2019-04-19"},
+		},
+		{
+			name: "related table",
+			cfg: Config{
+				Table:     "trips",
+				Relations: map[string]Relation{"author": {Table: "authors"}},
+			},
+			cond: map[string]interface{}{
+				"author__created_at__gt": Col("trips.created_at"),
+			},
+			wantSQL:  `(EXISTS (SELECT 1 FROM "authors" AS "author" WHERE "author"."id" = "trips"."author_id" AND ("author"."created_at" > "trips"."created_at")))`,
+			wantVars: []interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Strict = true
+			plan := WithConfig(tt.cfg).Where(tt.cond).Build()
+			if plan.Error != nil {
+				t.Fatalf("unexpected error: %+v", plan.Error)
+			}
+			if sql := plan.SQL(); sql != tt.wantSQL {
+				t.Errorf("sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if vars := plan.Vars(); !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+
+	// referenced columns must be allowed too
+	plan := WithConfig(Config{Strict: true, AllowedFields: map[string][]string{"name": nil}}).
+		Where(map[string]interface{}{"name": map[string]interface{}{"$col": "password"}}).Build()
+	var fe *UnknownFieldError
+	if !errors.As(plan.Error, &fe) || fe.Field != "password" {
+		t.Errorf("Error = %v, want unknown field password", plan.Error)
+	}
+
+	// lists are bound as a single var, so the column references can't be the items
+	for _, val := range []interface{}{
+		[]interface{}{"a", map[string]interface{}{"$col": "title"}},
+		[]ColumnValue{Col("title")},
+	} {
+		plan := WithConfig(Config{Strict: true}).Where(map[string]interface{}{"name__notin": val}).Build()
+		if !errors.Is(plan.Error, ErrInvalidValue) {
+			t.Errorf("Error = %v, want %v", plan.Error, ErrInvalidValue)
+		}
+	}
+}
+
+func TestExprValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		cond     interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name: "expression without args",
+			cond: map[string]interface{}{
+				"created_at__gte": Expr("NOW() - INTERVAL '7 days'"),
+				"name":            "Go",
+			},
+			wantSQL:  `("created_at" >= (NOW() - INTERVAL '7 days') AND "name" = ?)`,
+			wantVars: []interface{}{"Go"},
+		},
+		{
+			name: "expression with args in order",
+			cond: map[string]interface{}{
+				"a_budget__gte":    1000,
+				"b_name__iexact":   Expr("CONCAT(?, ?)", "Go", "pher"),
+				"c_name__endswith": Expr("lower(?)", "%pher"),
+				"d_date__between":  []interface{}{Expr("DATE(?)", "2019-04-13"), "2019-04-15"},
+			},
+			wantSQL:  `("a_budget" >= ? AND LOWER("b_name") = LOWER((CONCAT(?, ?))) AND "c_name" LIKE (lower(?)) AND "d_date" BETWEEN (DATE(?)) AND ?)`,
+			wantVars: []interface{}{1000, "Go", "pher", "%pher", "2019-04-13", "2019-04-15"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := WithConfig(Config{Strict: true}).Where(tt.cond).Build()
+			if plan.Error != nil {
+				t.Fatalf("unexpected error: %+v", plan.Error)
+			}
+			if sql := plan.SQL(); sql != tt.wantSQL {
+				t.Errorf("sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if vars := plan.Vars(); !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("vars = %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+
+	for _, val := range []interface{}{Expr("lower(?)"), []interface{}{Expr("lower(?)", "Go")}} {
+		plan := WithConfig(Config{Strict: true}).Where(map[string]interface{}{"name": val}).Build()
+		if !errors.Is(plan.Error, ErrInvalidValue) {
+			t.Errorf("Error = %v, want %v", plan.Error, ErrInvalidValue)
+		}
+	}
+}